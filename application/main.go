@@ -25,13 +25,31 @@ func main() {
 }
 
 func sequenceAnomalyDetection() {
-	detector := ecu.GetSequentialAnomalyDetector()
+	detector, err := ecu.LoadSequentialAnomalyDetector("./data/detector.json")
+	if err != nil {
+		panic(err)
+	}
+
+	// This file has real timestamps, samples are expected every second
+	if !detector.CheckTimestamps {
+		detector.SetTimestampCheck(2)
+	}
 
 	anomalyCount := 0
 	CSVFileReader("./data/data_sequential.csv", func(index int, data string) {
 
 		str := ParseCSVLine(data)
 
+		timestamp, err := strconv.ParseFloat(str[0], 64)
+		if err != nil {
+			return
+		}
+
+		brake, err := strconv.Atoi(str[1])
+		if err != nil {
+			return
+		}
+
 		rpm, err := strconv.Atoi(str[2])
 		if err != nil {
 			return
@@ -48,19 +66,21 @@ func sequenceAnomalyDetection() {
 		}
 
 		ecuData := ecu.ECUData{
-			RPM:   rpm,
-			Gear:  gear,
-			Speed: speed,
+			Timestamp: timestamp,
+			RPM:       rpm,
+			Gear:      gear,
+			Speed:     speed,
+			Brake:     brake,
 		}
 
-		isAnomaly, err := detector.AddData(ecuData)
+		report, err := detector.AddDataWithReport(ecuData)
 		if err != nil {
 			fmt.Printf("error: %v\n", err.Error())
 			return
 		}
 
-		if isAnomaly {
-			fmt.Printf("has anomaly at line %d\n", index)
+		if report.IsAnomaly {
+			fmt.Printf("has anomaly at line %d: %s\n", index, report)
 			anomalyCount++
 		}
 
@@ -82,7 +102,7 @@ func (individualDetector) generateData(destinationFileData string) {
 	}
 }
 
-func (individualDetector) generateModel(sourceFileTraining, destinationFileModel string) {
+func (d individualDetector) generateModel(sourceFileTraining, destinationFileModel string) {
 
 	// Load data
 	dataset, err := ml.LoadDataFromCSV(sourceFileTraining, ecu.CreateECUData)
@@ -94,7 +114,7 @@ func (individualDetector) generateModel(sourceFileTraining, destinationFileModel
 	trainData, testData := ml.SplitTrainTest(dataset, 0.8)
 
 	// Build tree
-	tree := trainData.BuildTree(0, 5)
+	tree := trainData.BuildTreeWithObserver(0, 5, ml.NewPrintObserver(os.Stdout))
 
 	// Test accuracy
 	accuracy := tree.GetPredictionAccuration(testData)
@@ -103,6 +123,9 @@ func (individualDetector) generateModel(sourceFileTraining, destinationFileModel
 	tree.SaveModel(destinationFileModel)
 
 	tree.PrintTree()
+
+	// Check the saved tree against the physical rules
+	d.verifyModel(destinationFileModel)
 }
 
 func (individualDetector) getPredictionAccuration(sourceFileModel, sourceFileData string) {
@@ -119,6 +142,19 @@ func (individualDetector) getPredictionAccuration(sourceFileModel, sourceFileDat
 	fmt.Printf("Accuracy: %.2f%%\n", tree.GetPredictionAccuration(dataset))
 }
 
+func (individualDetector) verifyModel(sourceFileModel string) {
+	tree, err := ml.LoadModel(sourceFileModel)
+	if err != nil {
+		panic(err)
+	}
+
+	report, err := ecu.NewECUComparator().VerifyTree(tree, ecu.DefaultECUDomain())
+	if err != nil {
+		panic(err)
+	}
+	report.PrintReport()
+}
+
 func CSVFileReader(filename string, readline func(index int, data string)) error {
 	file, err := os.Open(filename)
 	if err != nil {
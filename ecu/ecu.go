@@ -1,17 +1,22 @@
 package ecu
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"ml"
 	"strconv"
+	"time"
 )
 
 // Struktur data untuk ECU
 type ECUData struct {
-	RPM      int
-	Gear     int
-	Speed    int
-	IsAttack bool // true jika status=1
+	Timestamp float64 // detik
+	RPM       int
+	Gear      int
+	Speed     int
+	Brake     int  // brake force
+	IsAttack  bool // true jika status=1
 }
 
 func (e ECUData) IsAnomaly() bool { return e.IsAttack }
@@ -24,6 +29,8 @@ func (e ECUData) GetFeatureValue(feature int) int {
 		return e.Gear
 	case 2:
 		return e.Speed
+	case 3:
+		return e.Brake
 	default:
 		return 0
 	}
@@ -37,13 +44,17 @@ func (c ECUData) GetFeatureName(feature int) string {
 		return "gear"
 	case 2:
 		return "speed"
+	case 3:
+		return "brake"
 	default:
 		return ""
 	}
 }
 
+func (e ECUData) GetTimestamp() float64 { return e.Timestamp }
+
 func (e ECUData) GetFeatureCount() int {
-	return 3 // RPM, Gear, Speed
+	return 4 // RPM, Gear, Speed, Brake
 }
 
 func CreateECUData(values []string) (ml.FeatureProvider, error) {
@@ -72,19 +83,47 @@ func CreateECUData(values []string) (ml.FeatureProvider, error) {
 		return nil, fmt.Errorf("invalid Status value: %v", err)
 	}
 
+	// Optional timestamp column after the description
+	var timestamp float64
+	if len(values) > 5 && values[5] != "" {
+		timestamp, err = strconv.ParseFloat(values[5], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Timestamp value: %v", err)
+		}
+	}
+
+	// Optional brake force column after the timestamp
+	var brake int
+	if len(values) > 6 && values[6] != "" {
+		brake, err = strconv.Atoi(values[6])
+		if err != nil {
+			return nil, fmt.Errorf("invalid Brake value: %v", err)
+		}
+	}
+
 	return ECUData{
-		RPM:      rpm,
-		Gear:     gear,
-		Speed:    speed,
-		IsAttack: status == 1,
+		Timestamp: timestamp,
+		RPM:       rpm,
+		Gear:      gear,
+		Speed:     speed,
+		Brake:     brake,
+		IsAttack:  status == 1,
 	}, nil
 }
 
 func GetSequentialAnomalyDetector() *ml.WindowDetector {
+	return GetSequentialAnomalyDetectorForProfile(DefaultVehicleProfile())
+}
+
+// GetSequentialAnomalyDetectorForProfile creates the detector for a specific vehicle
+func GetSequentialAnomalyDetectorForProfile(profile VehicleProfile) *ml.WindowDetector {
 
 	detector := ml.NewWindowDetector(3)
 
-	configs := CreateECUConfigs()
+	// The timestamp check is off because CSV data without a timestamp column has every
+	// timestamp at 0. Callers with real timestamps enable it with SetTimestampCheck
+
+	configs := CreateECUConfigsForProfile(profile)
 	for _, config := range configs {
 		detector.AddFeatureConfig(config)
 	}
@@ -97,3 +136,27 @@ func GetSequentialAnomalyDetector() *ml.WindowDetector {
 
 	return detector
 }
+
+// LoadSequentialAnomalyDetector loads the detector from a configuration file,
+// or returns the default detector when the file does not exist
+func LoadSequentialAnomalyDetector(filename string) (*ml.WindowDetector, error) {
+	detector, err := ml.LoadDetectorFromConfig(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return GetSequentialAnomalyDetector(), nil
+	}
+	return detector, err
+}
+
+// GetDetectorManager creates a manager that gives every stream its own ECU detector
+func GetDetectorManager(ttl time.Duration, maxStreams int) *ml.DetectorManager {
+	return GetProfileDetectorManager(func(key ml.StreamKey) VehicleProfile {
+		return DefaultVehicleProfile()
+	}, ttl, maxStreams)
+}
+
+// GetProfileDetectorManager creates a manager that picks the vehicle profile per stream
+func GetProfileDetectorManager(profileFor func(key ml.StreamKey) VehicleProfile, ttl time.Duration, maxStreams int) *ml.DetectorManager {
+	return ml.NewDetectorManager(func(key ml.StreamKey) ml.SequentialDetector {
+		return GetSequentialAnomalyDetectorForProfile(profileFor(key))
+	}, ttl, maxStreams)
+}
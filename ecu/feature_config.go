@@ -1,32 +1,34 @@
 package ecu
 
 import (
+	"fmt"
 	"math"
 	"ml"
 )
 
+// Register the ECU comparators so detectors using them can be saved and restored
+func init() {
+	ml.RegisterComparator("ecu.rpm", func() any { return &RPMComparator{ECUComparator: NewECUComparator()} })
+	ml.RegisterComparator("ecu.gear", func() any { return &GearComparator{GearCount: DefaultVehicleProfile().GearCount} })
+	ml.RegisterComparator("ecu.speed", func() any { return &SpeedComparator{ECUComparator: NewECUComparator()} })
+	ml.RegisterComparator("ecu.brake", func() any { return &BrakeComparator{ECUComparator: NewECUComparator()} })
+}
+
+// ECUComparator holds the vehicle profile shared by the ECU comparators.
+// The profile fields are exported so they can be tuned from a detector configuration file
 type ECUComparator struct {
-	currentGear       int
-	maxRPMPerGear     map[int]int
-	speedRangePerGear map[int][2]int
+	currentGear int
+	VehicleProfile
 }
 
+// NewECUComparator uses the default vehicle profile
 func NewECUComparator() *ECUComparator {
+	return NewECUComparatorForProfile(DefaultVehicleProfile())
+}
+
+func NewECUComparatorForProfile(profile VehicleProfile) *ECUComparator {
 	return &ECUComparator{
-		maxRPMPerGear: map[int]int{
-			1: 4000,
-			2: 3500,
-			3: 3000,
-			4: 2500,
-			5: 2000,
-		},
-		speedRangePerGear: map[int][2]int{
-			1: {0, 20},
-			2: {15, 40},
-			3: {30, 70},
-			4: {50, 100},
-			5: {70, 150},
-		},
+		VehicleProfile: profile,
 	}
 }
 
@@ -39,51 +41,91 @@ type RPMComparator struct {
 }
 
 func (c *RPMComparator) Compare(prev, current float64) float64 {
-	// Check absolute change (max 1000 per second)
-	if math.Abs(current-prev) > 1000 {
-		return 1.0
+	score, _ := c.CompareWithReason(prev, current)
+	return score
+}
+
+func (c *RPMComparator) CompareWithReason(prev, current float64) (float64, string) {
+	return c.checkRPM(prev, current, c.currentGear, 1)
+}
+
+// CompareRecords uses the gear of the current record instead of currentGear
+// and the actual time between the records
+func (c *RPMComparator) CompareRecords(prev, current ml.SequentialProvider, window []ml.SequentialProvider) (float64, string) {
+	prevRPM, _ := ml.FeatureValueByName(prev, "rpm")
+	currentRPM, _ := ml.FeatureValueByName(current, "rpm")
+	gear, _ := ml.FeatureValueByName(current, "gear")
+	return c.checkRPM(prevRPM, currentRPM, int(gear), ml.ElapsedSeconds(prev, current))
+}
+
+func (c *RPMComparator) checkRPM(prev, current float64, gear int, elapsed float64) (float64, string) {
+	// Check rate of change
+	if rate := math.Abs(current-prev) / elapsed; rate > c.MaxRPMChange {
+		return 1.0, fmt.Sprintf("rpm changed %.0f per second, max %.0f", rate, c.MaxRPMChange)
 	}
 
-	// Check idle range (800-1000)
-	if current < 800 && c.currentGear > 0 {
-		return 1.0
+	// Check redline
+	if c.RedlineRPM > 0 && current > float64(c.RedlineRPM) {
+		return 1.0, fmt.Sprintf("rpm %.0f above redline %d", current, c.RedlineRPM)
 	}
 
-	// Check max RPM for current gear
-	if c.currentGear > 0 && current > float64(c.maxRPMPerGear[c.currentGear]) {
-		return 1.0
+	if gear <= 0 {
+		return 0.0, ""
 	}
 
-	// Check gear shift rules
-	if c.currentGear > 1 && current < 1500 {
-		return 1.0 // Should downshift
+	// Check the normal RPM range of the gear, the same range the generator and the verifier use
+	minRPM, maxRPM, exists := c.AllowedRPM(gear)
+	if !exists {
+		return 1.0, fmt.Sprintf("no rpm range for gear %d", gear)
 	}
 
-	shiftUpRPM := map[int]float64{
-		1: 3000,
-		2: 2800,
-		3: 2500,
-		4: 2200,
+	if current < float64(minRPM) {
+		if gear == 1 {
+			return 1.0, fmt.Sprintf("rpm %.0f below idle for gear %d", current, gear)
+		}
+		return 1.0, fmt.Sprintf("rpm %.0f too low for gear %d, should downshift", current, gear)
 	}
-	if rpm, exists := shiftUpRPM[c.currentGear]; exists && current > rpm {
-		return 1.0 // Should upshift
+
+	if current > float64(maxRPM) {
+		if rpm, exists := c.ShiftUpRPMPerGear[gear]; exists && rpm == maxRPM {
+			return 1.0, fmt.Sprintf("rpm %.0f above %d for gear %d, should upshift", current, rpm, gear)
+		}
+		return 1.0, fmt.Sprintf("rpm exceeds max %d for gear %d", maxRPM, gear)
 	}
 
-	return 0.0
+	return 0.0, ""
 }
 
-type GearComparator struct{}
+type GearComparator struct {
+	GearCount int // 0 means 5 gears
+}
 
 func (c *GearComparator) Compare(prev, current float64) float64 {
+	score, _ := c.CompareWithReason(prev, current)
+	return score
+}
+
+func (c *GearComparator) CompareWithReason(prev, current float64) (float64, string) {
+	gearCount := c.GearCount
+	if gearCount == 0 {
+		gearCount = 5
+	}
+
 	// Can only change by 1 at a time
 	if math.Abs(current-prev) > 1 {
-		return 1.0
+		return 1.0, fmt.Sprintf("gear changed from %.0f to %.0f, max 1 step", prev, current)
 	}
-	// Must be between 0-5
-	if current < 0 || current > 5 {
-		return 1.0
+	// Must be between 0 and the highest gear
+	if current < 0 || current > float64(gearCount) {
+		return 1.0, fmt.Sprintf("gear %.0f outside 0-%d", current, gearCount)
 	}
-	return 0.0
+	return 0.0, ""
+}
+
+func (c *GearComparator) CompareRecords(prev, current ml.SequentialProvider, window []ml.SequentialProvider) (float64, string) {
+	prevGear, _ := ml.FeatureValueByName(prev, "gear")
+	currentGear, _ := ml.FeatureValueByName(current, "gear")
+	return c.CompareWithReason(prevGear, currentGear)
 }
 
 type SpeedComparator struct {
@@ -91,41 +133,126 @@ type SpeedComparator struct {
 }
 
 func (c *SpeedComparator) Compare(prev, current float64) float64 {
-	// Check max change (5 km/h per second)
-	if math.Abs(current-prev) > 5 {
-		return 1.0
+	score, _ := c.CompareWithReason(prev, current)
+	return score
+}
+
+func (c *SpeedComparator) CompareWithReason(prev, current float64) (float64, string) {
+	return c.checkSpeed(prev, current, c.currentGear, 1)
+}
+
+// CompareRecords uses the gear of the current record instead of currentGear
+// and the actual time between the records
+func (c *SpeedComparator) CompareRecords(prev, current ml.SequentialProvider, window []ml.SequentialProvider) (float64, string) {
+	prevSpeed, _ := ml.FeatureValueByName(prev, "speed")
+	currentSpeed, _ := ml.FeatureValueByName(current, "speed")
+	gear, _ := ml.FeatureValueByName(current, "gear")
+	return c.checkSpeed(prevSpeed, currentSpeed, int(gear), ml.ElapsedSeconds(prev, current))
+}
+
+func (c *SpeedComparator) checkSpeed(prev, current float64, gear int, elapsed float64) (float64, string) {
+	// Check acceleration and deceleration
+	rate := (current - prev) / elapsed
+	if rate > c.MaxAccel {
+		return 1.0, fmt.Sprintf("speed increased %.1f km/h per second, max %.1f", rate, c.MaxAccel)
+	}
+	if -rate > c.MaxDecel {
+		return 1.0, fmt.Sprintf("speed decreased %.1f km/h per second, max %.1f", -rate, c.MaxDecel)
 	}
 
 	// Check speed range for current gear
-	if c.currentGear > 0 {
-		speedRange := c.speedRangePerGear[c.currentGear]
+	if gear > 0 {
+		speedRange := c.SpeedRangePerGear[gear]
 		if current < float64(speedRange[0]) || current > float64(speedRange[1]) {
-			return 1.0
+			return 1.0, fmt.Sprintf("speed %.0f outside %d-%d for gear %d", current, speedRange[0], speedRange[1], gear)
 		}
 	}
 
-	return 0.0
+	return 0.0, ""
+}
+
+// BrakeComparator checks how fast the brake force changes
+type BrakeComparator struct {
+	*ECUComparator
+}
+
+func (c *BrakeComparator) Compare(prev, current float64) float64 {
+	score, _ := c.CompareWithReason(prev, current)
+	return score
+}
+
+func (c *BrakeComparator) CompareWithReason(prev, current float64) (float64, string) {
+	return c.checkBrake(prev, current, 1)
+}
+
+// CompareRecords uses the actual time between the records
+func (c *BrakeComparator) CompareRecords(prev, current ml.SequentialProvider, window []ml.SequentialProvider) (float64, string) {
+	prevBrake, _ := ml.FeatureValueByName(prev, "brake")
+	currentBrake, _ := ml.FeatureValueByName(current, "brake")
+	return c.checkBrake(prevBrake, currentBrake, ml.ElapsedSeconds(prev, current))
+}
+
+func (c *BrakeComparator) checkBrake(prev, current float64, elapsed float64) (float64, string) {
+	if rate := math.Abs(current-prev) / elapsed; rate > c.MaxBrakeChange {
+		return 1.0, fmt.Sprintf("brake force changed %.0f per second, max %.0f", rate, c.MaxBrakeChange)
+	}
+	return 0.0, ""
 }
 
 // Function to create ECU configs
 func CreateECUConfigs() []ml.FeatureConfig {
-	ecuComp := NewECUComparator()
+	return CreateECUConfigsForProfile(DefaultVehicleProfile())
+}
+
+// CreateECUConfigsForProfile creates the ECU configs for a specific vehicle
+func CreateECUConfigsForProfile(profile VehicleProfile) []ml.FeatureConfig {
+	ecuComp := NewECUComparatorForProfile(profile)
 
 	return []ml.FeatureConfig{
 		{
-			Name:       "rpm",
-			Threshold:  0.5,
-			Comparator: &RPMComparator{ECUComparator: ecuComp},
+			Name:             "rpm",
+			Threshold:        0.5,
+			RecordComparator: &RPMComparator{ECUComparator: ecuComp},
 		},
 		{
-			Name:       "gear",
-			Threshold:  0.5,
-			Comparator: &GearComparator{},
+			Name:             "gear",
+			Threshold:        0.5,
+			RecordComparator: &GearComparator{GearCount: profile.GearCount},
 		},
 		{
-			Name:       "speed",
-			Threshold:  0.5,
-			Comparator: &SpeedComparator{ECUComparator: ecuComp},
+			Name:             "speed",
+			Threshold:        0.5,
+			RecordComparator: &SpeedComparator{ECUComparator: ecuComp},
+		},
+		{
+			Name:             "brake",
+			Threshold:        0.5,
+			RecordComparator: &BrakeComparator{ECUComparator: ecuComp},
 		},
 	}
 }
+
+// CreateDriftConfigs creates EWMA and CUSUM configs for RPM and speed, calibrated
+// on a normal driving sequence. Add them next to CreateECUConfigs to catch slow sensor drift
+func CreateDriftConfigs(calibration []ECUData) ([]ml.FeatureConfig, error) {
+	sequence := toSequence(calibration)
+
+	var configs []ml.FeatureConfig
+	for _, feature := range []string{"rpm", "speed"} {
+		ewma, err := ml.CalibrateEWMA(feature, sequence)
+		if err != nil {
+			return nil, err
+		}
+		cusum, err := ml.CalibrateCUSUM(feature, sequence)
+		if err != nil {
+			return nil, err
+		}
+
+		configs = append(configs,
+			ml.FeatureConfig{Name: feature + ".ewma", Threshold: 3, RecordComparator: ewma},
+			ml.FeatureConfig{Name: feature + ".cusum", Threshold: 5, RecordComparator: cusum},
+		)
+	}
+
+	return configs, nil
+}
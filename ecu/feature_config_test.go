@@ -0,0 +1,43 @@
+package ecu
+
+import (
+	"encoding/json"
+	"ml"
+	"testing"
+)
+
+func TestComparatorParamsReplaceMaps(t *testing.T) {
+	comparator, err := ml.NewComparator("ecu.rpm", json.RawMessage(`{"MaxRPMPerGear": {"1": 4500}, "RedlineRPM": 7000}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rpm := comparator.(*RPMComparator)
+	if len(rpm.MaxRPMPerGear) != 1 || rpm.MaxRPMPerGear[1] != 4500 {
+		t.Errorf("max rpm table not replaced: %v", rpm.MaxRPMPerGear)
+	}
+	if rpm.RedlineRPM != 7000 || len(rpm.SpeedRangePerGear) != DefaultVehicleProfile().GearCount {
+		t.Errorf("other params must keep their defaults: %+v", rpm.VehicleProfile)
+	}
+}
+
+func TestUntrainedComparatorsAreRejected(t *testing.T) {
+	if _, err := ml.NewComparator("ecu.markov", nil); err == nil {
+		t.Error("expected error for markov model without transitions")
+	}
+	if _, err := ml.NewComparator("hmm", nil); err == nil {
+		t.Error("expected error for hmm without threshold")
+	}
+
+	model := NewGearMarkovModel(DefaultVehicleProfile())
+	if err := model.Train([][]ECUData{normalDrive()}); err != nil {
+		t.Fatal(err)
+	}
+	params, err := json.Marshal(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ml.NewComparator("ecu.markov", params); err != nil {
+		t.Errorf("trained markov model rejected: %v", err)
+	}
+}
@@ -0,0 +1,310 @@
+package ecu
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"ml"
+	"os"
+	"sort"
+)
+
+func init() {
+	ml.RegisterComparator("ecu.markov", func() any { return NewGearMarkovModel(DefaultVehicleProfile()) })
+}
+
+// Motion of the vehicle between two samples
+const (
+	MotionDecelerating = -1
+	MotionSteady       = 0
+	MotionAccelerating = 1
+)
+
+// GearState is the discrete state of the gear-transition Markov model
+type GearState struct {
+	Gear      int
+	RPMBand   int
+	SpeedBand int
+	Motion    int // MotionDecelerating, MotionSteady or MotionAccelerating
+	LastShift int // direction of the latest gear change within RecentShift samples, 0 if none
+}
+
+// Key is the compact form used in the transition tables
+func (s GearState) Key() string {
+	return fmt.Sprintf("%d:%d:%d:%d:%d", s.Gear, s.RPMBand, s.SpeedBand, s.Motion, s.LastShift)
+}
+
+func (s GearState) String() string {
+	motion := map[int]string{MotionDecelerating: "decelerating", MotionSteady: "steady", MotionAccelerating: "accelerating"}[s.Motion]
+	shift := map[int]string{-1: ", after downshift", 0: "", 1: ", after upshift"}[s.LastShift]
+	return fmt.Sprintf("gear %d rpm band %d speed band %d %s%s", s.Gear, s.RPMBand, s.SpeedBand, motion, shift)
+}
+
+// GearMarkovModel is a first-order Markov chain over GearState learned from normal driving.
+// The recent shift direction is part of the state, so oscillation like 2→3→2→3 and upshifting
+// while decelerating or braking show up as improbable transitions.
+// As a RecordComparator the score is the surprisal (negative log-likelihood) of the
+// latest transition, with the rest of the window providing its context
+type GearMarkovModel struct {
+	GearCount      int
+	RPMBands       []int   // RPM band boundaries, ascending
+	SpeedBands     []int   // speed band boundaries in km/h, ascending
+	AccelThreshold float64 // km/h per second that counts as accelerating or decelerating
+	RecentShift    int     // number of samples a gear change stays part of the state
+	Smoothing      float64 // additive smoothing for unseen transitions
+
+	Counts map[string]map[string]int
+	Totals map[string]int
+
+	// Threshold is the calibrated surprisal of a normal transition
+	Threshold float64
+}
+
+// NewGearMarkovModel creates an untrained model with bands derived from the profile
+func NewGearMarkovModel(profile VehicleProfile) *GearMarkovModel {
+	return &GearMarkovModel{
+		GearCount:      profile.GearCount,
+		RPMBands:       []int{profile.IdleRPM[1], profile.DownshiftRPM, profile.RedlineRPM / 2, profile.RedlineRPM},
+		SpeedBands:     []int{1, 20, 50, 90},
+		AccelThreshold: 1,
+		RecentShift:    5,
+		Smoothing:      0.1,
+		Counts:         make(map[string]map[string]int),
+		Totals:         make(map[string]int),
+	}
+}
+
+func band(value float64, boundaries []int) int {
+	for i, boundary := range boundaries {
+		if value < float64(boundary) {
+			return i
+		}
+	}
+	return len(boundaries)
+}
+
+// States converts a sequence into GearStates. Sample i looks back at most RecentShift
+// samples for the latest gear change, and at sample i-1 for motion
+func (m *GearMarkovModel) States(sequence []ml.SequentialProvider) []GearState {
+	states := make([]GearState, len(sequence))
+	lastShift, lastShiftAt := 0, -1
+	for i, data := range sequence {
+		rpm, _ := ml.FeatureValueByName(data, "rpm")
+		gear, _ := ml.FeatureValueByName(data, "gear")
+		speed, _ := ml.FeatureValueByName(data, "speed")
+
+		state := GearState{
+			Gear:      int(gear),
+			RPMBand:   band(rpm, m.RPMBands),
+			SpeedBand: band(speed, m.SpeedBands),
+		}
+
+		if i > 0 {
+			prevSpeed, _ := ml.FeatureValueByName(sequence[i-1], "speed")
+			accel := (speed - prevSpeed) / ml.ElapsedSeconds(sequence[i-1], data)
+			switch {
+			case accel > m.AccelThreshold:
+				state.Motion = MotionAccelerating
+			case accel < -m.AccelThreshold:
+				state.Motion = MotionDecelerating
+			}
+
+			// The brake pedal counts as decelerating when the stream has it
+			if brake, exists := ml.FeatureValueByName(data, "brake"); exists && brake > 0 {
+				state.Motion = MotionDecelerating
+			}
+		}
+
+		if i > 0 && state.Gear != states[i-1].Gear {
+			lastShift, lastShiftAt = sign(state.Gear-states[i-1].Gear), i
+		}
+		if lastShiftAt >= 0 && i-lastShiftAt < m.RecentShift {
+			state.LastShift = lastShift
+		}
+
+		states[i] = state
+	}
+	return states
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+// Train counts the transitions of normal driving sequences
+func (m *GearMarkovModel) Train(sequences [][]ECUData) error {
+	if m.Counts == nil {
+		m.Counts = make(map[string]map[string]int)
+	}
+	if m.Totals == nil {
+		m.Totals = make(map[string]int)
+	}
+
+	transitions := 0
+	for _, sequence := range sequences {
+		states := m.States(toSequence(sequence))
+		for i := 1; i < len(states); i++ {
+			from, to := states[i-1].Key(), states[i].Key()
+			if m.Counts[from] == nil {
+				m.Counts[from] = make(map[string]int)
+			}
+			m.Counts[from][to]++
+			m.Totals[from]++
+			transitions++
+		}
+	}
+
+	if transitions == 0 {
+		return fmt.Errorf("no transitions in training data")
+	}
+	return nil
+}
+
+// CheckTrained rejects a model from a configuration that has no transition counts
+func (m *GearMarkovModel) CheckTrained() error {
+	if len(m.Counts) == 0 {
+		return fmt.Errorf("gear markov model has no transitions, train it first")
+	}
+	return nil
+}
+
+// Number of possible states, used to spread the smoothing mass
+func (m *GearMarkovModel) stateCount() float64 {
+	return float64((m.GearCount + 1) * (len(m.RPMBands) + 1) * (len(m.SpeedBands) + 1) * 3 * 3)
+}
+
+// TransitionLogLikelihood returns log P(to | from) with additive smoothing
+func (m *GearMarkovModel) TransitionLogLikelihood(from, to GearState) float64 {
+	smoothing := m.Smoothing
+	if smoothing <= 0 {
+		smoothing = 0.1
+	}
+
+	count := float64(m.Counts[from.Key()][to.Key()])
+	total := float64(m.Totals[from.Key()])
+	return math.Log((count + smoothing) / (total + smoothing*m.stateCount()))
+}
+
+// SequenceLogLikelihood returns the log-likelihood of every transition in the sequence.
+// The result has one value less than the sequence
+func (m *GearMarkovModel) SequenceLogLikelihood(sequence []ml.SequentialProvider) []float64 {
+	states := m.States(sequence)
+	if len(states) < 2 {
+		return nil
+	}
+
+	logLikelihoods := make([]float64, len(states)-1)
+	for i := 1; i < len(states); i++ {
+		logLikelihoods[i-1] = m.TransitionLogLikelihood(states[i-1], states[i])
+	}
+	return logLikelihoods
+}
+
+// Calibrate sets Threshold so that about falsePositiveRate of the transitions in the
+// normal sequences exceed it, scored with the same window size as the detector
+func (m *GearMarkovModel) Calibrate(sequences [][]ECUData, windowSize int, falsePositiveRate float64) error {
+	if windowSize < 2 {
+		return fmt.Errorf("window size must be at least 2, got %d", windowSize)
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return fmt.Errorf("false positive rate must be between 0 and 1, got %f", falsePositiveRate)
+	}
+
+	var scores []float64
+	for _, sequence := range sequences {
+		for end := windowSize; end <= len(sequence); end++ {
+			score, _, _, _ := m.latestSurprisal(toSequence(sequence[end-windowSize : end]))
+			scores = append(scores, score)
+		}
+	}
+
+	if len(scores) == 0 {
+		return fmt.Errorf("no calibration windows of size %d", windowSize)
+	}
+
+	sort.Float64s(scores)
+	index := int(math.Ceil(float64(len(scores))*(1-falsePositiveRate))) - 1
+	m.Threshold = scores[min(max(index, 0), len(scores)-1)]
+	return nil
+}
+
+// Surprisal of the latest transition in the window. The earlier samples
+// only provide the motion and recent shift of the states
+func (m *GearMarkovModel) latestSurprisal(window []ml.SequentialProvider) (float64, GearState, GearState, bool) {
+	states := m.States(window)
+	if len(states) < 2 {
+		return 0, GearState{}, GearState{}, false
+	}
+
+	from, to := states[len(states)-2], states[len(states)-1]
+	return -m.TransitionLogLikelihood(from, to), from, to, true
+}
+
+// CompareRecords scores the latest transition. Use Threshold as the threshold of the FeatureConfig
+func (m *GearMarkovModel) CompareRecords(prev, current ml.SequentialProvider, window []ml.SequentialProvider) (float64, string) {
+	score, from, to, ok := m.latestSurprisal(window)
+	if !ok {
+		return 0, ""
+	}
+	return score, fmt.Sprintf("improbable gear transition %s -> %s (p=%.4f)", from, to, math.Exp(-score))
+}
+
+// CreateGearMarkovConfig trains and calibrates a model on normal sequences
+// and wraps it in a feature config for a detector with the given window size
+func CreateGearMarkovConfig(profile VehicleProfile, normal [][]ECUData, windowSize int, falsePositiveRate float64) (ml.FeatureConfig, error) {
+	model := NewGearMarkovModel(profile)
+	if err := model.Train(normal); err != nil {
+		return ml.FeatureConfig{}, err
+	}
+	if err := model.Calibrate(normal, windowSize, falsePositiveRate); err != nil {
+		return ml.FeatureConfig{}, err
+	}
+
+	return ml.FeatureConfig{
+		Name:             "gear_sequence",
+		Threshold:        model.Threshold,
+		RecordComparator: model,
+	}, nil
+}
+
+// SaveGearMarkovModel saves the model as JSON
+func (m *GearMarkovModel) SaveGearMarkovModel(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
+
+// LoadGearMarkovModel loads a model saved with SaveGearMarkovModel
+func LoadGearMarkovModel(filename string) (*GearMarkovModel, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var model GearMarkovModel
+	if err := json.NewDecoder(file).Decode(&model); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+func toSequence(data []ECUData) []ml.SequentialProvider {
+	sequence := make([]ml.SequentialProvider, len(data))
+	for i, d := range data {
+		sequence[i] = d
+	}
+	return sequence
+}
@@ -0,0 +1,65 @@
+package ecu
+
+import "testing"
+
+// normalDrive accelerates through gears 1 to 3, cruises, brakes in gear 3 and cruises again
+func normalDrive() []ECUData {
+	var drive []ECUData
+	add := func(rpm, gear, speed, brake int) {
+		drive = append(drive, ECUData{Timestamp: float64(len(drive)), RPM: rpm, Gear: gear, Speed: speed, Brake: brake})
+	}
+
+	speed := 0
+	for gear := 1; gear <= 3; gear++ {
+		for i := 0; i < 6; i++ {
+			add(1500+i*200, gear, speed, 0)
+			speed += 3
+		}
+	}
+	for i := 0; i < 6; i++ {
+		add(2000, 3, speed, 0)
+	}
+	for i := 0; i < 4; i++ {
+		speed -= 3
+		add(1800, 3, speed, 40)
+	}
+	for i := 0; i < 6; i++ {
+		add(1800, 3, speed, 0)
+	}
+	return drive
+}
+
+func TestGearMarkovFlagsUpshiftWhileBraking(t *testing.T) {
+	drive := normalDrive()
+	training := [][]ECUData{drive, drive, drive}
+
+	model := NewGearMarkovModel(DefaultVehicleProfile())
+	if err := model.Train(training); err != nil {
+		t.Fatal(err)
+	}
+	// The window must reach one sample before the oldest shift the scored states look back to,
+	// otherwise normal windows lose their recent shift and look improbable
+	windowSize := model.RecentShift + 2
+	if err := model.Calibrate(training, windowSize, 0.01); err != nil {
+		t.Fatal(err)
+	}
+
+	// The 2 -> 3 upshift of the normal drive, once as driven and once with the brake pressed
+	upshift := append([]ECUData(nil), drive[13-windowSize:13]...)
+	if upshift[windowSize-2].Gear != 2 || upshift[windowSize-1].Gear != 3 {
+		t.Fatalf("window is not an upshift: %+v", upshift)
+	}
+	braking := append([]ECUData(nil), upshift...)
+	for i := range braking {
+		braking[i].Brake = 40
+	}
+
+	normalScore, _ := model.CompareRecords(nil, nil, toSequence(upshift))
+	brakingScore, _ := model.CompareRecords(nil, nil, toSequence(braking))
+	if brakingScore <= model.Threshold {
+		t.Errorf("upshift while braking scored %.2f, threshold %.2f", brakingScore, model.Threshold)
+	}
+	if brakingScore <= normalScore {
+		t.Errorf("brake does not change the score: %.2f with brake, %.2f without", brakingScore, normalScore)
+	}
+}
@@ -0,0 +1,294 @@
+package ecu
+
+import (
+	"fmt"
+	"math"
+	"ml"
+)
+
+func init() {
+	ml.RegisterComparator("ecu.kalman", func() any { return NewKalmanComparator() })
+}
+
+// KalmanComparator runs a Kalman filter over the vehicle speed and acceleration and
+// checks each reading against a drivetrain model: in gear, speed ≈ RPM * SpeedPerRPM[gear].
+// The score is the squared Mahalanobis distance of the innovation divided by the outlier
+// threshold for the number of measurements, so a single spoofed signal that disagrees with
+// the others is caught even when it is in range on its own, and scores above 1 are outliers.
+// It keeps state, so create a new instance for every stream
+type KalmanComparator struct {
+	SpeedPerRPM map[int]float64 // km/h per RPM for each gear
+	ClutchRPM   float64         // at or below this RPM the clutch may slip and RPM is not used
+
+	SpeedNoise float64 // measurement noise of speed, km/h
+	RPMNoise   float64 // measurement noise of RPM including drivetrain slip
+	AccelNoise float64 // process noise, change of acceleration in km/h per second²
+
+	// Threshold is the squared Mahalanobis distance above which a reading of speed and RPM is
+	// treated as an outlier and not used to update the filter. SpeedThreshold is the same for
+	// readings where only speed is measured, which have one degree of freedom instead of two
+	Threshold      float64
+	SpeedThreshold float64
+	// MaxOutliers consecutive outliers reset the filter to the current reading
+	MaxOutliers int
+
+	// State: speed and acceleration with their covariance
+	State       [2]float64
+	Covariance  [2][2]float64
+	Initialized bool
+	Outliers    int
+}
+
+// NewKalmanComparator uses the default vehicle profile
+func NewKalmanComparator() *KalmanComparator {
+	return NewKalmanComparatorForProfile(DefaultVehicleProfile())
+}
+
+// NewKalmanComparatorForProfile derives the drivetrain ratios from the profile
+func NewKalmanComparatorForProfile(profile VehicleProfile) *KalmanComparator {
+	ratios := make(map[int]float64)
+	for gear := 1; gear <= profile.GearCount; gear++ {
+		if ratio, exists := profile.SpeedPerRPM(gear); exists {
+			ratios[gear] = ratio
+		}
+	}
+
+	return &KalmanComparator{
+		SpeedPerRPM:    ratios,
+		ClutchRPM:      float64(profile.IdleRPM[1]),
+		SpeedNoise:     2,
+		RPMNoise:       300,
+		AccelNoise:     3,
+		Threshold:      13.82, // chi-square with 2 degrees of freedom at p = 0.001
+		SpeedThreshold: 10.83, // chi-square with 1 degree of freedom at p = 0.001
+		MaxOutliers:    5,
+	}
+}
+
+// CreateDrivetrainConfig creates a feature config that runs the Kalman filter for a profile.
+// The score is already relative to the outlier threshold, so the config threshold is 1
+func CreateDrivetrainConfig(profile VehicleProfile) ml.FeatureConfig {
+	return ml.FeatureConfig{
+		Name:             "drivetrain",
+		Threshold:        1,
+		RecordComparator: NewKalmanComparatorForProfile(profile),
+	}
+}
+
+// threshold returns the outlier threshold for the number of measurements
+func (c *KalmanComparator) threshold(measurements int) float64 {
+	if measurements == 1 {
+		return c.SpeedThreshold
+	}
+	return c.Threshold
+}
+
+// Calibrate fits SpeedPerRPM per gear with least squares on normal driving data
+// and sets RPMNoise to the standard deviation of the RPM residual
+func (c *KalmanComparator) Calibrate(normal []ECUData) error {
+	type sums struct{ rpmSpeed, rpmRPM float64 }
+	perGear := make(map[int]*sums)
+	for _, data := range normal {
+		if data.Gear <= 0 || float64(data.RPM) <= c.ClutchRPM {
+			continue
+		}
+		s, exists := perGear[data.Gear]
+		if !exists {
+			s = &sums{}
+			perGear[data.Gear] = s
+		}
+		s.rpmSpeed += float64(data.RPM) * float64(data.Speed)
+		s.rpmRPM += float64(data.RPM) * float64(data.RPM)
+	}
+
+	if len(perGear) == 0 {
+		return fmt.Errorf("no calibration data in gear above clutch RPM %.0f", c.ClutchRPM)
+	}
+
+	ratios := make(map[int]float64)
+	for gear, s := range perGear {
+		if s.rpmSpeed > 0 {
+			ratios[gear] = s.rpmSpeed / s.rpmRPM
+		}
+	}
+
+	var sumSq float64
+	var count int
+	for _, data := range normal {
+		ratio, exists := ratios[data.Gear]
+		if !exists || float64(data.RPM) <= c.ClutchRPM {
+			continue
+		}
+		residual := float64(data.RPM) - float64(data.Speed)/ratio
+		sumSq += residual * residual
+		count++
+	}
+
+	c.SpeedPerRPM = ratios
+	if count > 1 {
+		c.RPMNoise = math.Max(math.Sqrt(sumSq/float64(count-1)), 1)
+	}
+	c.Reset()
+	return nil
+}
+
+// Reset clears the filter state
+func (c *KalmanComparator) Reset() {
+	c.State = [2]float64{}
+	c.Covariance = [2][2]float64{}
+	c.Initialized = false
+	c.Outliers = 0
+}
+
+func (c *KalmanComparator) CompareRecords(prev, current ml.SequentialProvider, window []ml.SequentialProvider) (float64, string) {
+	speed, speedExists := ml.FeatureValueByName(current, "speed")
+	rpm, rpmExists := ml.FeatureValueByName(current, "rpm")
+	gear, _ := ml.FeatureValueByName(current, "gear")
+	if !speedExists || !rpmExists {
+		return 0, ""
+	}
+
+	if !c.Initialized {
+		c.initialize(speed)
+		return 0, ""
+	}
+
+	c.predict(ml.ElapsedSeconds(prev, current))
+
+	// Measurements: speed always, RPM only when the drivetrain is engaged
+	z := []float64{speed}
+	h := []float64{1}
+	r := []float64{c.SpeedNoise * c.SpeedNoise}
+	ratio, engaged := c.SpeedPerRPM[int(gear)]
+	if engaged && ratio > 0 && rpm > c.ClutchRPM {
+		z = append(z, rpm)
+		h = append(h, 1/ratio)
+		r = append(r, c.RPMNoise*c.RPMNoise)
+	} else {
+		engaged = false
+	}
+
+	distance, gain := c.innovation(z, h, r)
+	threshold := c.threshold(len(z))
+
+	if distance > threshold {
+		expected := c.State[0]
+
+		// Spoofed readings must not pull the filter, unless the outliers persist
+		c.Outliers++
+		if c.MaxOutliers > 0 && c.Outliers >= c.MaxOutliers {
+			c.initialize(speed)
+		}
+
+		if engaged {
+			return distance / threshold, fmt.Sprintf("speed %.0f and rpm %.0f inconsistent in gear %.0f, expected speed %.1f and rpm %.0f (mahalanobis² %.1f, max %.1f)",
+				speed, rpm, gear, expected, expected/ratio, distance, threshold)
+		}
+		return distance / threshold, fmt.Sprintf("speed %.0f inconsistent with motion, expected %.1f (mahalanobis² %.1f, max %.1f)", speed, expected, distance, threshold)
+	}
+
+	c.Outliers = 0
+	c.update(z, h, gain)
+	return distance / threshold, ""
+}
+
+func (c *KalmanComparator) initialize(speed float64) {
+	c.State = [2]float64{speed, 0}
+	c.Covariance = [2][2]float64{
+		{c.SpeedNoise * c.SpeedNoise, 0},
+		{0, 25},
+	}
+	c.Initialized = true
+	c.Outliers = 0
+}
+
+// Constant acceleration model over dt seconds
+func (c *KalmanComparator) predict(dt float64) {
+	p := c.Covariance
+	c.State = [2]float64{c.State[0] + dt*c.State[1], c.State[1]}
+
+	// P = F P F' + Q
+	p00 := p[0][0] + dt*(p[1][0]+p[0][1]) + dt*dt*p[1][1]
+	p01 := p[0][1] + dt*p[1][1]
+	p10 := p[1][0] + dt*p[1][1]
+	p11 := p[1][1]
+
+	q := c.AccelNoise * c.AccelNoise
+	c.Covariance = [2][2]float64{
+		{p00 + q*dt*dt*dt*dt/4, p01 + q*dt*dt*dt/2},
+		{p10 + q*dt*dt*dt/2, p11 + q*dt*dt},
+	}
+}
+
+// Every measurement only observes the speed state: z[i] = h[i] * speed + noise.
+// Returns the squared Mahalanobis distance and the Kalman gain
+func (c *KalmanComparator) innovation(z, h, r []float64) (float64, [][2]float64) {
+	p := c.Covariance
+	m := len(z)
+
+	// S = H P H' + R
+	s := make([][]float64, m)
+	y := make([]float64, m)
+	for i := 0; i < m; i++ {
+		s[i] = make([]float64, m)
+		for j := 0; j < m; j++ {
+			s[i][j] = h[i] * h[j] * p[0][0]
+		}
+		s[i][i] += r[i]
+		y[i] = z[i] - h[i]*c.State[0]
+	}
+
+	inverse := invertSmall(s)
+
+	var distance float64
+	for i := 0; i < m; i++ {
+		for j := 0; j < m; j++ {
+			distance += y[i] * inverse[i][j] * y[j]
+		}
+	}
+
+	// K = P H' S^-1, stored per measurement
+	gain := make([][2]float64, m)
+	for j := 0; j < m; j++ {
+		var weight float64
+		for i := 0; i < m; i++ {
+			weight += h[i] * inverse[i][j]
+		}
+		gain[j] = [2]float64{p[0][0] * weight, p[1][0] * weight}
+	}
+
+	return distance, gain
+}
+
+func (c *KalmanComparator) update(z, h []float64, gain [][2]float64) {
+	predicted := c.State[0]
+
+	var g0, g1 float64
+	for j := range z {
+		y := z[j] - h[j]*predicted
+		c.State[0] += gain[j][0] * y
+		c.State[1] += gain[j][1] * y
+		g0 += gain[j][0] * h[j]
+		g1 += gain[j][1] * h[j]
+	}
+
+	// P = (I - K H) P, where K H only has a first column
+	p := c.Covariance
+	c.Covariance = [2][2]float64{
+		{(1 - g0) * p[0][0], (1 - g0) * p[0][1]},
+		{p[1][0] - g1*p[0][0], p[1][1] - g1*p[0][1]},
+	}
+}
+
+// Inverse of a 1x1 or 2x2 matrix
+func invertSmall(s [][]float64) [][]float64 {
+	if len(s) == 1 {
+		return [][]float64{{1 / s[0][0]}}
+	}
+
+	det := s[0][0]*s[1][1] - s[0][1]*s[1][0]
+	return [][]float64{
+		{s[1][1] / det, -s[0][1] / det},
+		{-s[1][0] / det, s[0][0] / det},
+	}
+}
@@ -0,0 +1,26 @@
+package ecu
+
+import "testing"
+
+// Readings with only speed measured have one degree of freedom and use SpeedThreshold
+func TestKalmanSpeedOnlyThreshold(t *testing.T) {
+	between := false
+	for jump := 1; jump <= 40; jump++ {
+		comparator := NewKalmanComparator()
+		neutral := ECUData{Timestamp: 0, RPM: 900, Gear: 0, Speed: 50}
+		jumped := ECUData{Timestamp: 1, RPM: 900, Gear: 0, Speed: 50 + jump}
+		comparator.CompareRecords(neutral, neutral, nil)
+
+		score, reason := comparator.CompareRecords(neutral, jumped, nil)
+		distance := score * comparator.SpeedThreshold
+		if flagged := reason != ""; flagged != (distance > comparator.SpeedThreshold) {
+			t.Errorf("jump %d: mahalanobis² %.2f flagged %v", jump, distance, flagged)
+		}
+		if distance > comparator.SpeedThreshold && distance <= comparator.Threshold {
+			between = true
+		}
+	}
+	if !between {
+		t.Error("no jump between the 1 and 2 degree of freedom thresholds")
+	}
+}
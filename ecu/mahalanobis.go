@@ -0,0 +1,31 @@
+package ecu
+
+import "ml"
+
+// TrainMahalanobisDetector fits a Mahalanobis baseline on normal readings and picks the
+// threshold for the target false positive rate. With perGear each gear gets its own
+// rpm/speed model, because the normal rpm-speed relation depends on the gear.
+// The brake is left out because BrakeComparator already checks it over time
+func TrainMahalanobisDetector(normal []ECUData, perGear bool, falsePositiveRate float64) (*ml.MahalanobisDetector, error) {
+	dataset := make(ml.DataSet, len(normal))
+	for i, data := range normal {
+		dataset[i] = data
+	}
+
+	detector := ml.NewMahalanobisDetector()
+	detector.Features = []int{0, 1, 2}
+	detector.FeatureNames = []string{"rpm", "gear", "speed"}
+	if perGear {
+		detector.Features = []int{0, 2}
+		detector.FeatureNames = []string{"rpm", "speed"}
+		detector.GroupFeature = 1
+	}
+
+	if err := detector.Train(dataset); err != nil {
+		return nil, err
+	}
+	if err := detector.Calibrate(dataset, falsePositiveRate); err != nil {
+		return nil, err
+	}
+	return detector, nil
+}
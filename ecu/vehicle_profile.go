@@ -0,0 +1,259 @@
+package ecu
+
+import (
+	"fmt"
+	"sort"
+)
+
+// VehicleProfile describes the physical limits of one vehicle model.
+// It is shared by the ECU comparators, the verifier and the data generator
+type VehicleProfile struct {
+	Name      string
+	GearCount int
+
+	IdleRPM      [2]int // idle range while stationary
+	RedlineRPM   int
+	DownshiftRPM int // below this RPM in gear 2 and up the driver should downshift
+
+	MaxRPMPerGear     map[int]int
+	SpeedRangePerGear map[int][2]int
+	ShiftUpRPMPerGear map[int]int
+
+	MaxRPMChange   float64 // RPM per second
+	MaxAccel       float64 // km/h per second
+	MaxDecel       float64 // km/h per second
+	MaxBrakeChange float64 // brake force per second
+}
+
+// DefaultVehicleProfile returns the limits the detector has always used
+func DefaultVehicleProfile() VehicleProfile {
+	return VehicleProfile{
+		Name:         "default",
+		GearCount:    5,
+		IdleRPM:      [2]int{800, 1000},
+		RedlineRPM:   6000,
+		DownshiftRPM: 1500,
+		MaxRPMPerGear: map[int]int{
+			1: 4000,
+			2: 3500,
+			3: 3000,
+			4: 2500,
+			5: 2000,
+		},
+		SpeedRangePerGear: map[int][2]int{
+			1: {0, 20},
+			2: {15, 40},
+			3: {30, 70},
+			4: {50, 100},
+			5: {70, 150},
+		},
+		ShiftUpRPMPerGear: map[int]int{
+			1: 3000,
+			2: 2800,
+			3: 2500,
+			4: 2200,
+		},
+		MaxRPMChange:   1000,
+		MaxAccel:       5,
+		MaxDecel:       5,
+		MaxBrakeChange: 20,
+	}
+}
+
+// CityCarProfile is a small petrol hatchback that revs higher but is slower
+func CityCarProfile() VehicleProfile {
+	return VehicleProfile{
+		Name:         "city-car",
+		GearCount:    5,
+		IdleRPM:      [2]int{750, 950},
+		RedlineRPM:   6500,
+		DownshiftRPM: 1400,
+		MaxRPMPerGear: map[int]int{
+			1: 4500,
+			2: 4000,
+			3: 3500,
+			4: 3000,
+			5: 2800,
+		},
+		SpeedRangePerGear: map[int][2]int{
+			1: {0, 25},
+			2: {15, 45},
+			3: {30, 70},
+			4: {45, 95},
+			5: {60, 140},
+		},
+		ShiftUpRPMPerGear: map[int]int{
+			1: 3200,
+			2: 3000,
+			3: 2800,
+			4: 2600,
+		},
+		MaxRPMChange:   1200,
+		MaxAccel:       4,
+		MaxDecel:       8,
+		MaxBrakeChange: 20,
+	}
+}
+
+// SportsCarProfile is a six-speed car with a high redline
+func SportsCarProfile() VehicleProfile {
+	return VehicleProfile{
+		Name:         "sports-car",
+		GearCount:    6,
+		IdleRPM:      [2]int{850, 1100},
+		RedlineRPM:   7500,
+		DownshiftRPM: 1800,
+		MaxRPMPerGear: map[int]int{
+			1: 7000,
+			2: 7000,
+			3: 6500,
+			4: 6000,
+			5: 5000,
+			6: 4000,
+		},
+		SpeedRangePerGear: map[int][2]int{
+			1: {0, 50},
+			2: {20, 85},
+			3: {40, 120},
+			4: {60, 160},
+			5: {80, 200},
+			6: {100, 250},
+		},
+		ShiftUpRPMPerGear: map[int]int{
+			1: 6000,
+			2: 6000,
+			3: 5500,
+			4: 5000,
+			5: 4500,
+		},
+		MaxRPMChange:   2000,
+		MaxAccel:       10,
+		MaxDecel:       12,
+		MaxBrakeChange: 30,
+	}
+}
+
+// TruckProfile is a diesel light truck with a low redline
+func TruckProfile() VehicleProfile {
+	return VehicleProfile{
+		Name:         "truck",
+		GearCount:    5,
+		IdleRPM:      [2]int{600, 800},
+		RedlineRPM:   4000,
+		DownshiftRPM: 1200,
+		MaxRPMPerGear: map[int]int{
+			1: 3000,
+			2: 2800,
+			3: 2600,
+			4: 2400,
+			5: 2200,
+		},
+		SpeedRangePerGear: map[int][2]int{
+			1: {0, 15},
+			2: {10, 30},
+			3: {20, 50},
+			4: {35, 75},
+			5: {55, 110},
+		},
+		ShiftUpRPMPerGear: map[int]int{
+			1: 2400,
+			2: 2300,
+			3: 2200,
+			4: 2000,
+		},
+		MaxRPMChange:   600,
+		MaxAccel:       3,
+		MaxDecel:       5,
+		MaxBrakeChange: 15,
+	}
+}
+
+var vehicleProfiles = map[string]func() VehicleProfile{
+	"default":    DefaultVehicleProfile,
+	"city-car":   CityCarProfile,
+	"sports-car": SportsCarProfile,
+	"truck":      TruckProfile,
+}
+
+// GetVehicleProfile returns a fresh copy of a built-in profile by name
+func GetVehicleProfile(name string) (VehicleProfile, error) {
+	profile, exists := vehicleProfiles[name]
+	if !exists {
+		return VehicleProfile{}, fmt.Errorf("unknown vehicle profile: %s", name)
+	}
+	return profile(), nil
+}
+
+// VehicleProfileNames returns the names of all built-in profiles
+func VehicleProfileNames() []string {
+	names := make([]string, 0, len(vehicleProfiles))
+	for name := range vehicleProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AllowedRPM returns the RPM range of a normal reading in the given gear.
+// Gear 1 may run down to idle, higher gears must stay above DownshiftRPM.
+// The upper bound is the shift-up RPM when the gear has one, because above it
+// the driver should upshift; the top gear is limited by MaxRPMPerGear.
+// The generator, the verifier and RPMComparator all use this range
+func (p VehicleProfile) AllowedRPM(gear int) (int, int, bool) {
+	maxRPM, exists := p.MaxRPMPerGear[gear]
+	if !exists {
+		return 0, 0, false
+	}
+	if shiftUpRPM, exists := p.ShiftUpRPMPerGear[gear]; exists {
+		maxRPM = min(maxRPM, shiftUpRPM)
+	}
+	if gear > 1 {
+		return p.DownshiftRPM, maxRPM, true
+	}
+	return p.IdleRPM[0], maxRPM, true
+}
+
+// AllowedSpeed returns the speed range of a normal reading in the given gear
+func (p VehicleProfile) AllowedSpeed(gear int) (int, int, bool) {
+	speedRange, exists := p.SpeedRangePerGear[gear]
+	if !exists {
+		return 0, 0, false
+	}
+	return speedRange[0], speedRange[1], true
+}
+
+// Validate checks that the tables cover every gear
+func (p VehicleProfile) Validate() error {
+	if p.GearCount < 1 {
+		return fmt.Errorf("profile %s: gear count must be at least 1", p.Name)
+	}
+	for gear := 1; gear <= p.GearCount; gear++ {
+		if _, exists := p.MaxRPMPerGear[gear]; !exists {
+			return fmt.Errorf("profile %s: no max RPM for gear %d", p.Name, gear)
+		}
+		if _, exists := p.SpeedRangePerGear[gear]; !exists {
+			return fmt.Errorf("profile %s: no speed range for gear %d", p.Name, gear)
+		}
+		if minRPM, maxRPM, _ := p.AllowedRPM(gear); minRPM > maxRPM {
+			return fmt.Errorf("profile %s: empty RPM range for gear %d", p.Name, gear)
+		}
+		if minSpeed, maxSpeed, _ := p.AllowedSpeed(gear); minSpeed > maxSpeed {
+			return fmt.Errorf("profile %s: empty speed range for gear %d", p.Name, gear)
+		}
+	}
+	if p.IdleRPM[0] > p.IdleRPM[1] {
+		return fmt.Errorf("profile %s: invalid idle range %v", p.Name, p.IdleRPM)
+	}
+	return nil
+}
+
+// SpeedPerRPM estimates the drivetrain ratio of a gear in km/h per RPM from the
+// middle of its speed and RPM ranges. Calibrate on real data when it is available
+func (p VehicleProfile) SpeedPerRPM(gear int) (float64, bool) {
+	minRPM, maxRPM, rpmExists := p.AllowedRPM(gear)
+	minSpeed, maxSpeed, speedExists := p.AllowedSpeed(gear)
+	if !rpmExists || !speedExists || minRPM+maxRPM == 0 {
+		return 0, false
+	}
+	return float64(minSpeed+maxSpeed) / float64(minRPM+maxRPM), true
+}
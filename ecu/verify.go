@@ -0,0 +1,203 @@
+package ecu
+
+import (
+	"fmt"
+	"ml"
+	"strings"
+)
+
+// DefaultECUDomain returns the value space the verifier enumerates:
+// rpm 0-8000, gear 0-5, speed 0-200 km/h, brake released
+func DefaultECUDomain() []ml.FeatureRange {
+	return DefaultVehicleProfile().Domain()
+}
+
+// Domain returns the value space of the profile: rpm up to 2000 above redline,
+// every gear including neutral and speed up to 50 km/h above the top gear.
+// The brake is fixed at 0 because its rules are rate limits that a single reading cannot break
+func (p VehicleProfile) Domain() []ml.FeatureRange {
+	maxSpeed := 0
+	for _, speedRange := range p.SpeedRangePerGear {
+		maxSpeed = max(maxSpeed, speedRange[1])
+	}
+
+	return []ml.FeatureRange{
+		{Min: 0, Max: p.RedlineRPM + 2000}, // rpm
+		{Min: 0, Max: p.GearCount},         // gear
+		{Min: 0, Max: maxSpeed + 50},       // speed
+		{Min: 0, Max: 0},                   // brake
+	}
+}
+
+// Region is an inclusive box over rpm, gear and speed
+type Region struct {
+	RPM   ml.FeatureRange
+	Gear  ml.FeatureRange
+	Speed ml.FeatureRange
+}
+
+// Points returns the number of integer readings inside the region
+func (r Region) Points() int {
+	return r.RPM.Size() * r.Gear.Size() * r.Speed.Size()
+}
+
+func (r Region) String() string {
+	return fmt.Sprintf("rpm %d-%d, gear %d-%d, speed %d-%d",
+		r.RPM.Min, r.RPM.Max, r.Gear.Min, r.Gear.Max, r.Speed.Min, r.Speed.Max)
+}
+
+// Violation is a part of a leaf where the tree disagrees with the physical rules
+type Violation struct {
+	Region     Region
+	Prediction bool // what the tree says (true = anomaly)
+	Reason     string
+}
+
+// VerificationReport summarizes how well a tree agrees with the ECU rules
+type VerificationReport struct {
+	Violations []Violation
+
+	TotalPoints        int // readings in the domain
+	AgreePoints        int // readings where tree and rules agree
+	FalseNormalPoints  int // tree says normal, rules say impossible
+	FalseAnomalyPoints int // tree says anomaly, rules say valid
+}
+
+// Coverage returns the percentage of the domain where tree and rules agree
+func (r *VerificationReport) Coverage() float64 {
+	if r.TotalPoints == 0 {
+		return 0
+	}
+	return float64(r.AgreePoints) / float64(r.TotalPoints) * 100
+}
+
+func (r *VerificationReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Coverage: %.2f%% (%d of %d readings)\n", r.Coverage(), r.AgreePoints, r.TotalPoints)
+	fmt.Fprintf(&sb, "Normal but impossible: %d readings\n", r.FalseNormalPoints)
+	fmt.Fprintf(&sb, "Anomaly but valid: %d readings\n", r.FalseAnomalyPoints)
+	for _, v := range r.Violations {
+		fmt.Fprintf(&sb, "- [%s] %s: %s\n",
+			map[bool]string{true: "Attack", false: "Normal"}[v.Prediction], v.Region, v.Reason)
+	}
+	return sb.String()
+}
+
+func (r *VerificationReport) PrintReport() {
+	fmt.Print(r.String())
+}
+
+// VerifyTree enumerates the leaf regions of the tree and compares them with
+// the per-gear RPM and speed tables of the profile. A reading is considered valid when:
+//   - gear 0 (neutral): speed is 0
+//   - other gears: rpm and speed are within VehicleProfile.AllowedRPM and AllowedSpeed
+//
+// The domain must start with the rpm, gear and speed ranges, as returned by Domain
+func (e *ECUComparator) VerifyTree(tree *ml.Node, domain []ml.FeatureRange) (*VerificationReport, error) {
+	if len(domain) < 3 {
+		return nil, fmt.Errorf("domain needs rpm, gear and speed ranges, got %d ranges", len(domain))
+	}
+
+	report := &VerificationReport{}
+
+	for _, leaf := range tree.LeafRegions(domain) {
+		rpmRange, gearRange, speedRange := leaf.Ranges[0], leaf.Ranges[1], leaf.Ranges[2]
+
+		// Rules are per gear, so check each gear inside the leaf separately
+		for gear := gearRange.Min; gear <= gearRange.Max; gear++ {
+			region := Region{
+				RPM:   rpmRange,
+				Gear:  ml.FeatureRange{Min: gear, Max: gear},
+				Speed: speedRange,
+			}
+
+			valid, invalid := e.splitRegion(region)
+
+			report.TotalPoints += region.Points()
+
+			validPoints := 0
+			if valid != nil {
+				validPoints = valid.Points()
+			}
+
+			if leaf.Prediction {
+				report.AgreePoints += region.Points() - validPoints
+				report.FalseAnomalyPoints += validPoints
+				if valid != nil {
+					report.Violations = append(report.Violations, Violation{
+						Region:     *valid,
+						Prediction: true,
+						Reason:     fmt.Sprintf("valid reading for gear %d", gear),
+					})
+				}
+				continue
+			}
+
+			report.AgreePoints += validPoints
+			for _, v := range invalid {
+				report.FalseNormalPoints += v.Region.Points()
+				v.Prediction = false
+				report.Violations = append(report.Violations, v)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// splitRegion splits a single-gear region into the part allowed by the rules
+// and the parts that are physically impossible
+func (e *ECUComparator) splitRegion(region Region) (*Region, []Violation) {
+	gear := region.Gear.Min
+
+	var allowedRPM, allowedSpeed ml.FeatureRange
+	if gear == 0 {
+		allowedRPM = region.RPM
+		allowedSpeed = ml.FeatureRange{Min: 0, Max: 0}
+	} else {
+		minRPM, maxRPM, exists := e.AllowedRPM(gear)
+		minSpeed, maxSpeed, speedExists := e.AllowedSpeed(gear)
+		if !exists || !speedExists {
+			return nil, []Violation{{Region: region, Reason: fmt.Sprintf("unknown gear %d", gear)}}
+		}
+		allowedRPM = ml.FeatureRange{Min: minRPM, Max: maxRPM}
+		allowedSpeed = ml.FeatureRange{Min: minSpeed, Max: maxSpeed}
+	}
+
+	var invalid []Violation
+	add := func(rpm, speed ml.FeatureRange, reason string) {
+		if rpm.IsEmpty() || speed.IsEmpty() {
+			return
+		}
+		invalid = append(invalid, Violation{
+			Region: Region{RPM: rpm, Gear: region.Gear, Speed: speed},
+			Reason: reason,
+		})
+	}
+
+	// RPM below and above the allowed range, for every speed
+	add(ml.FeatureRange{Min: region.RPM.Min, Max: min(region.RPM.Max, allowedRPM.Min-1)}, region.Speed,
+		fmt.Sprintf("rpm below %d for gear %d", allowedRPM.Min, gear))
+	add(ml.FeatureRange{Min: max(region.RPM.Min, allowedRPM.Max+1), Max: region.RPM.Max}, region.Speed,
+		fmt.Sprintf("rpm exceeds max %d for gear %d", allowedRPM.Max, gear))
+
+	// Speed outside the allowed range, for the allowed RPM only (avoid double counting)
+	rpmInside := region.RPM.Intersect(allowedRPM)
+	speedReason := fmt.Sprintf("speed outside %d-%d for gear %d", allowedSpeed.Min, allowedSpeed.Max, gear)
+	if gear == 0 {
+		speedReason = "speed in neutral"
+	}
+	add(rpmInside, ml.FeatureRange{Min: region.Speed.Min, Max: min(region.Speed.Max, allowedSpeed.Min-1)}, speedReason)
+	add(rpmInside, ml.FeatureRange{Min: max(region.Speed.Min, allowedSpeed.Max+1), Max: region.Speed.Max}, speedReason)
+
+	valid := Region{
+		RPM:   rpmInside,
+		Gear:  region.Gear,
+		Speed: region.Speed.Intersect(allowedSpeed),
+	}
+	if valid.RPM.IsEmpty() || valid.Speed.IsEmpty() {
+		return nil, invalid
+	}
+
+	return &valid, invalid
+}
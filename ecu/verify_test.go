@@ -0,0 +1,18 @@
+package ecu
+
+import (
+	"ml"
+	"testing"
+)
+
+func TestVerifyTreeRejectsShortDomain(t *testing.T) {
+	tree := &ml.Node{IsLeaf: true, Prediction: false}
+	domain := DefaultECUDomain()
+
+	if _, err := NewECUComparator().VerifyTree(tree, domain[:2]); err == nil {
+		t.Error("expected error for a domain without speed")
+	}
+	if _, err := NewECUComparator().VerifyTree(tree, domain); err != nil {
+		t.Error(err)
+	}
+}
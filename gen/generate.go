@@ -1,51 +1,67 @@
 package gen
 
 import (
+	"ecu"
 	"encoding/csv"
 	"fmt"
 	"math/rand"
 	"os"
+	"strconv"
 )
 
+// maxBrakeForce is the brake force with the pedal fully pressed
+const maxBrakeForce = 100
+
 // VehicleData represents a single row of vehicle data
 type VehicleData struct {
+	Timestamp   float64 // seconds, only set by GenerateSequence
 	RPM         int
 	Gear        int
 	Speed       int
+	Brake       int
 	Status      int
 	Description string
 }
 
 // Generator contains methods for generating vehicle data
 type Generator struct {
-	data map[string]bool // Used to ensure uniqueness
+	data    map[string]bool // Used to ensure uniqueness
+	profile ecu.VehicleProfile
 }
 
-// NewGenerator creates a new Generator instance
+// NewGenerator creates a new Generator instance using the default vehicle profile
 func NewGenerator() *Generator {
+	return NewGeneratorForProfile(ecu.DefaultVehicleProfile())
+}
+
+// NewGeneratorForProfile creates a Generator that follows the limits of a vehicle profile
+func NewGeneratorForProfile(profile ecu.VehicleProfile) *Generator {
 	return &Generator{
-		data: make(map[string]bool),
+		data:    make(map[string]bool),
+		profile: profile,
+	}
+}
+
+// randomBetween returns a random integer in [low, high]
+func randomBetween(low, high int) int {
+	if high <= low {
+		return low
 	}
+	return rand.Intn(high-low+1) + low
 }
 
 // generateNormalCase generates a single normal case
 func (g *Generator) generateNormalCase() *VehicleData {
-	gear := rand.Intn(5) + 1 // 1-5
+	gear := randomBetween(1, g.profile.GearCount)
 
-	// Calculate appropriate speed range based on gear
-	minSpeed := max(5, (gear-1)*15)
-	maxSpeed := min(gear*40, 200)
-	speed := rand.Intn(maxSpeed-minSpeed+1) + minSpeed
-
-	// Calculate appropriate RPM range
-	minRPM := max(800, 1000*speed/(gear*40))
-	maxRPM := 5500
-	rpm := rand.Intn(maxRPM-minRPM+1) + minRPM
+	// Speed and RPM within the allowed range for the gear
+	minSpeed, maxSpeed, _ := g.profile.AllowedSpeed(gear)
+	minRPM, maxRPM, _ := g.profile.AllowedRPM(gear)
 
 	return &VehicleData{
-		RPM:         rpm,
+		RPM:         randomBetween(minRPM, maxRPM),
 		Gear:        gear,
-		Speed:       speed,
+		Speed:       randomBetween(minSpeed, maxSpeed),
 		Status:      0,
 		Description: "normal",
 	}
@@ -53,49 +69,57 @@ func (g *Generator) generateNormalCase() *VehicleData {
 
 // generateAnomalyCase generates a single anomaly case
 func (g *Generator) generateAnomalyCase() *VehicleData {
+	p := g.profile
 	anomalyType := rand.Intn(5) + 1
 	var data VehicleData
 
 	switch anomalyType {
 	case 1: // Over-revving
+		gear := randomBetween(1, min(3, p.GearCount))
+		minSpeed, maxSpeed, _ := p.AllowedSpeed(gear)
 		data = VehicleData{
-			RPM:         rand.Intn(1500) + 5501, // 5501-7000
-			Gear:        rand.Intn(3) + 1,       // 1-3
-			Speed:       rand.Intn(100) + 20,    // 20-120
+			RPM:         randomBetween(p.RedlineRPM+1, p.RedlineRPM+1500),
+			Gear:        gear,
+			Speed:       randomBetween(minSpeed, maxSpeed),
 			Status:      1,
 			Description: "Over-revving",
 		}
 	case 2: // Stalling
+		gear := randomBetween(1, p.GearCount)
+		minSpeed, maxSpeed, _ := p.AllowedSpeed(gear)
 		data = VehicleData{
-			RPM:         rand.Intn(400) + 400, // 400-799
-			Gear:        rand.Intn(5) + 1,     // 1-5
-			Speed:       rand.Intn(21) + 10,   // 10-30
+			RPM:         randomBetween(p.IdleRPM[0]/2, p.IdleRPM[0]-1),
+			Gear:        gear,
+			Speed:       randomBetween(minSpeed, maxSpeed),
 			Status:      1,
 			Description: "Stalling",
 		}
 	case 3: // Gear-speed mismatch
-		gear := rand.Intn(2) + 1 // 1-2
+		gear := randomBetween(1, min(2, p.GearCount))
+		minRPM, maxRPM, _ := p.AllowedRPM(gear)
+		_, maxSpeed, _ := p.AllowedSpeed(gear)
 		data = VehicleData{
-			RPM:         rand.Intn(2000) + 2000, // 2000-4000
+			RPM:         randomBetween(minRPM, maxRPM),
 			Gear:        gear,
-			Speed:       rand.Intn(71) + 50 + gear*50, // 50-120
+			Speed:       randomBetween(maxSpeed+30, maxSpeed+100), // Far above the range for the gear
 			Status:      1,
 			Description: "Gear-speed mismatch",
 		}
 	case 4: // Neutral with speed
 		data = VehicleData{
-			RPM:         rand.Intn(2200) + 800, // 800-3000
-			Gear:        0,                     // Neutral
-			Speed:       rand.Intn(61) + 20,    // 20-80
+			RPM:         randomBetween(p.IdleRPM[0], p.DownshiftRPM*2),
+			Gear:        0, // Neutral
+			Speed:       randomBetween(20, 80),
 			Status:      1,
 			Description: "Neutral with speed",
 		}
 	case 5: // RPM too low for speed-gear
-		gear := rand.Intn(3) + 3 // 3-5
+		gear := randomBetween(min(3, p.GearCount), p.GearCount)
+		minSpeed, maxSpeed, _ := p.AllowedSpeed(gear)
 		data = VehicleData{
-			RPM:         rand.Intn(701) + 800, // 800-1500
+			RPM:         randomBetween(p.IdleRPM[0], p.DownshiftRPM-1),
 			Gear:        gear,
-			Speed:       rand.Intn(16) + gear*30, // Higher speed for gear
+			Speed:       randomBetween(minSpeed, maxSpeed),
 			Status:      1,
 			Description: "RPM too low for speed-gear",
 		}
@@ -136,6 +160,52 @@ func (g *Generator) GenerateData(normalCount, anomalyCount int) []VehicleData {
 	return result
 }
 
+// GenerateSequence generates a normal drive in one gear with count samples, interval seconds apart.
+// Every step stays within the rate limits of the profile: the brake force changes by at most
+// MaxBrakeChange per second, and the car slows down while the brake is pressed
+func (g *Generator) GenerateSequence(count int, interval float64) []VehicleData {
+	p := g.profile
+	gear := randomBetween(1, p.GearCount)
+	minRPM, maxRPM, _ := p.AllowedRPM(gear)
+	minSpeed, maxSpeed, _ := p.AllowedSpeed(gear)
+
+	// Largest change per sample, rounded down so the integer values never exceed the limits
+	rpmStep := int(p.MaxRPMChange * interval / 2)
+	accelStep := int(p.MaxAccel * interval)
+	decelStep := int(p.MaxDecel * interval)
+	brakeStep := int(p.MaxBrakeChange * interval)
+
+	rpm := randomBetween(minRPM, maxRPM)
+	speed := randomBetween(minSpeed, maxSpeed)
+	brake := 0
+
+	result := make([]VehicleData, count)
+	for i := range result {
+		if i > 0 {
+			brake = clamp(brake+randomBetween(-brakeStep, brakeStep), 0, maxBrakeForce)
+			if brake > 0 {
+				speed -= decelStep * brake / maxBrakeForce
+			} else {
+				speed += randomBetween(-min(accelStep, decelStep), accelStep)
+			}
+			speed = clamp(speed, minSpeed, maxSpeed)
+			rpm = clamp(rpm+randomBetween(-rpmStep, rpmStep), minRPM, maxRPM)
+		}
+
+		result[i] = VehicleData{
+			Timestamp:   float64(i) * interval,
+			RPM:         rpm,
+			Gear:        gear,
+			Speed:       speed,
+			Brake:       brake,
+			Status:      0,
+			Description: "normal",
+		}
+	}
+
+	return result
+}
+
 // SaveToCSV saves the generated data to a CSV file
 func SaveToCSV(data []VehicleData, filename string) error {
 	file, err := os.Create(filename)
@@ -148,7 +218,7 @@ func SaveToCSV(data []VehicleData, filename string) error {
 	defer writer.Flush()
 
 	// Write header
-	if err := writer.Write([]string{"rpm", "gear", "speed", "status", "description"}); err != nil {
+	if err := writer.Write([]string{"rpm", "gear", "speed", "status", "description", "timestamp", "brake"}); err != nil {
 		return err
 	}
 
@@ -160,6 +230,8 @@ func SaveToCSV(data []VehicleData, filename string) error {
 			fmt.Sprintf("%d", row.Speed),
 			fmt.Sprintf("%d", row.Status),
 			row.Description,
+			strconv.FormatFloat(row.Timestamp, 'f', -1, 64),
+			fmt.Sprintf("%d", row.Brake),
 		}); err != nil {
 			return err
 		}
@@ -182,3 +254,7 @@ func max(a, b int) int {
 	}
 	return b
 }
+
+func clamp(value, low, high int) int {
+	return max(low, min(value, high))
+}
@@ -0,0 +1,85 @@
+package gen
+
+import (
+	"ecu"
+	"ml"
+	"testing"
+)
+
+// Generated normal data must be accepted by the comparators and the verifier of the same profile
+func TestGeneratedNormalDataIsNotFlagged(t *testing.T) {
+	for _, name := range ecu.VehicleProfileNames() {
+		profile, err := ecu.GetVehicleProfile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		comparator := ecu.NewECUComparatorForProfile(profile)
+		rpm := &ecu.RPMComparator{ECUComparator: comparator}
+		speed := &ecu.SpeedComparator{ECUComparator: comparator}
+		normal := &ml.Node{IsLeaf: true, Prediction: false}
+
+		for _, row := range NewGeneratorForProfile(profile).GenerateData(2000, 0) {
+			data := ecu.ECUData{RPM: row.RPM, Gear: row.Gear, Speed: row.Speed}
+
+			if score, reason := rpm.CompareRecords(data, data, nil); score > 0 {
+				t.Fatalf("%s: rpm comparator flags normal %+v: %s", name, row, reason)
+			}
+			if score, reason := speed.CompareRecords(data, data, nil); score > 0 {
+				t.Fatalf("%s: speed comparator flags normal %+v: %s", name, row, reason)
+			}
+
+			// A tree that always says normal must not disagree with the rules on this reading
+			domain := []ml.FeatureRange{
+				{Min: row.RPM, Max: row.RPM},
+				{Min: row.Gear, Max: row.Gear},
+				{Min: row.Speed, Max: row.Speed},
+				{Min: row.Brake, Max: row.Brake},
+			}
+			report, err := comparator.VerifyTree(normal, domain)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.FalseNormalPoints > 0 {
+				t.Fatalf("%s: verifier rejects normal %+v: %s", name, row, report.Violations[0].Reason)
+			}
+		}
+	}
+}
+
+// A generated drive must pass the sequential detector of the same profile, brake included
+func TestGeneratedSequenceIsNotFlagged(t *testing.T) {
+	for _, name := range ecu.VehicleProfileNames() {
+		profile, err := ecu.GetVehicleProfile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		detector := ecu.GetSequentialAnomalyDetectorForProfile(profile)
+		detector.SetTimestampCheck(2)
+
+		braked := false
+		for _, row := range NewGeneratorForProfile(profile).GenerateSequence(500, 1) {
+			data := ecu.ECUData{Timestamp: row.Timestamp, RPM: row.RPM, Gear: row.Gear, Speed: row.Speed, Brake: row.Brake}
+			report, err := detector.AddDataWithReport(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.IsAnomaly {
+				t.Fatalf("%s: detector flags normal %+v: %s", name, row, report)
+			}
+			braked = braked || row.Brake > 0
+		}
+		if !braked {
+			t.Errorf("%s: the brake is never pressed", name)
+		}
+
+		// Pressing the brake faster than the profile allows is flagged
+		brake := &ecu.BrakeComparator{ECUComparator: ecu.NewECUComparatorForProfile(profile)}
+		released := ecu.ECUData{Timestamp: 0}
+		pressed := ecu.ECUData{Timestamp: 1, Brake: int(profile.MaxBrakeChange) + 1}
+		if score, _ := brake.CompareRecords(released, pressed, nil); score == 0 {
+			t.Errorf("%s: brake change of %d per second not flagged", name, pressed.Brake)
+		}
+	}
+}
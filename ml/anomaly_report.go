@@ -0,0 +1,138 @@
+package ml
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReasonComparator adalah FeatureComparator yang juga bisa menjelaskan rule yang dilanggar
+type ReasonComparator interface {
+	FeatureComparator
+	// CompareWithReason sama dengan Compare, ditambah deskripsi rule yang terpicu.
+	// Deskripsi kosong jika tidak ada rule yang terpicu
+	CompareWithReason(prev, current float64) (float64, string)
+}
+
+// Severity adalah tingkat keparahan anomali secara keseluruhan
+type Severity int
+
+const (
+	SeverityNone Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityNone:
+		return "none"
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// MarshalText menulis severity sebagai nama agar mudah dibaca di JSON
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText membaca severity dari nama
+func (s *Severity) UnmarshalText(text []byte) error {
+	for severity := SeverityNone; severity <= SeverityHigh; severity++ {
+		if severity.String() == string(text) {
+			*s = severity
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity: %s", text)
+}
+
+// FeatureResult adalah hasil satu pengecekan untuk satu feature
+type FeatureResult struct {
+	Feature   string
+	Check     string // "change" atau nama statistik window
+	Score     float64
+	Threshold float64
+	Previous  float64
+	Current   float64
+	Exceeded  bool
+	Rule      string   // rule yang terpicu, kosong jika tidak ada
+	Severity  Severity // severity dari rule bernama, SeverityNone jika dihitung dari skor
+	Error     string   // error evaluasi rule bernama atau ErrorComparator, dianggap tidak terpicu
+}
+
+// AnomalyReport adalah hasil lengkap deteksi untuk satu data
+type AnomalyReport struct {
+	Ready     bool // false jika history belum mencapai WindowSize
+	IsAnomaly bool
+	Severity  Severity
+	Results   []FeatureResult
+}
+
+// Fired mengembalikan hasil yang melewati threshold
+func (r *AnomalyReport) Fired() []FeatureResult {
+	var fired []FeatureResult
+	for _, result := range r.Results {
+		if result.Exceeded {
+			fired = append(fired, result)
+		}
+	}
+	return fired
+}
+
+func (r *AnomalyReport) String() string {
+	if !r.Ready {
+		return "not enough history"
+	}
+	if !r.IsAnomaly {
+		return "normal"
+	}
+
+	rules := make([]string, 0, len(r.Results))
+	for _, result := range r.Fired() {
+		rules = append(rules, result.Rule)
+	}
+	return fmt.Sprintf("anomaly (severity=%s): %s", r.Severity, strings.Join(rules, "; "))
+}
+
+// Hitung severity dari jumlah feature yang terpicu dan seberapa jauh skornya
+func (r *AnomalyReport) calculateSeverity() Severity {
+	features := make(map[string]bool)
+	maxRatio := 0.0
+	explicit := SeverityNone
+	for _, result := range r.Fired() {
+		features[result.Feature] = true
+
+		// Rule bernama menentukan severity-nya sendiri
+		if result.Severity != SeverityNone {
+			explicit = max(explicit, result.Severity)
+			continue
+		}
+
+		ratio := 2.0
+		if result.Threshold > 0 {
+			ratio = result.Score / result.Threshold
+		}
+		maxRatio = max(maxRatio, ratio)
+	}
+
+	var severity Severity
+	switch {
+	case len(features) == 0:
+		return SeverityNone
+	case len(features) > 1:
+		severity = SeverityHigh
+	case maxRatio >= 2:
+		severity = SeverityMedium
+	default:
+		severity = SeverityLow
+	}
+	return max(severity, explicit)
+}
@@ -0,0 +1,347 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+)
+
+// Optimizer adalah algoritma update bobot autoencoder
+type Optimizer string
+
+const (
+	OptimizerSGD  Optimizer = "sgd"
+	OptimizerAdam Optimizer = "adam"
+)
+
+// AutoencoderOptions adalah parameter training autoencoder
+type AutoencoderOptions struct {
+	// Hidden adalah ukuran layer encoder sampai bottleneck, decoder memakai urutan kebalikannya.
+	// Contoh {8, 2} untuk 3 feature menghasilkan 3 -> 8 -> 2 -> 8 -> 3
+	Hidden       []int
+	Epochs       int
+	BatchSize    int
+	LearningRate float64
+	Optimizer    Optimizer
+	Seed         int64 // seed yang sama menghasilkan model yang sama
+}
+
+// DefaultAutoencoderOptions cocok untuk data ECU dengan sedikit feature
+func DefaultAutoencoderOptions() AutoencoderOptions {
+	return AutoencoderOptions{
+		Hidden:       []int{8, 2},
+		Epochs:       100,
+		BatchSize:    32,
+		LearningRate: 0.01,
+		Optimizer:    OptimizerAdam,
+		Seed:         1,
+	}
+}
+
+// DenseLayer adalah layer fully connected. Weights berukuran output x input.
+// Layer tersembunyi memakai tanh, layer output linear
+type DenseLayer struct {
+	Weights [][]float64
+	Bias    []float64
+	Linear  bool `json:",omitempty"`
+}
+
+func (l *DenseLayer) forward(input []float64) []float64 {
+	output := make([]float64, len(l.Weights))
+	for i, weights := range l.Weights {
+		sum := l.Bias[i]
+		for j, w := range weights {
+			sum += w * input[j]
+		}
+		if !l.Linear {
+			sum = math.Tanh(sum)
+		}
+		output[i] = sum
+	}
+	return output
+}
+
+// Autoencoder mendeteksi anomali dari reconstruction error: model dilatih hanya dengan data normal,
+// sehingga data yang tidak mirip data normal sulit direkonstruksi. IsAnomaly data latih diabaikan.
+// Untuk data sekuensial, latih dengan DataSet hasil WindowTransformer.Transform
+type Autoencoder struct {
+	Options   AutoencoderOptions
+	Scaler    *FeatureScaler
+	Layers    []*DenseLayer
+	Losses    []float64 // rata-rata loss setiap epoch
+	Threshold float64
+}
+
+// TrainAutoencoder melatih autoencoder dengan mini-batch SGD atau Adam
+func TrainAutoencoder(normal DataSet, options AutoencoderOptions) (*Autoencoder, error) {
+	if len(normal) == 0 {
+		return nil, fmt.Errorf("dataset is empty")
+	}
+	if options.Epochs < 1 || options.BatchSize < 1 || options.LearningRate <= 0 {
+		return nil, fmt.Errorf("epochs, batch size and learning rate must be positive")
+	}
+	if options.Optimizer != OptimizerSGD && options.Optimizer != OptimizerAdam {
+		return nil, fmt.Errorf("unknown optimizer: %s", options.Optimizer)
+	}
+	for _, size := range options.Hidden {
+		if size < 1 {
+			return nil, fmt.Errorf("hidden layer size must be positive, got %d", size)
+		}
+	}
+
+	scaler, err := FitScaler(normal)
+	if err != nil {
+		return nil, err
+	}
+
+	model := &Autoencoder{Options: options, Scaler: scaler}
+	inputs := make([][]float64, len(normal))
+	for i, data := range normal {
+		inputs[i] = scaler.Transform(data)
+	}
+
+	// Ukuran layer: input, encoder, decoder (cermin encoder tanpa bottleneck), output
+	sizes := []int{len(inputs[0])}
+	sizes = append(sizes, options.Hidden...)
+	for i := len(options.Hidden) - 2; i >= 0; i-- {
+		sizes = append(sizes, options.Hidden[i])
+	}
+	sizes = append(sizes, len(inputs[0]))
+
+	random := rand.New(rand.NewSource(options.Seed))
+	for i := 1; i < len(sizes); i++ {
+		// Inisialisasi Xavier
+		limit := math.Sqrt(6 / float64(sizes[i-1]+sizes[i]))
+		layer := &DenseLayer{Weights: make([][]float64, sizes[i]), Bias: make([]float64, sizes[i]), Linear: i == len(sizes)-1}
+		for j := range layer.Weights {
+			layer.Weights[j] = make([]float64, sizes[i-1])
+			for k := range layer.Weights[j] {
+				layer.Weights[j][k] = (random.Float64()*2 - 1) * limit
+			}
+		}
+		model.Layers = append(model.Layers, layer)
+	}
+
+	trainer := newAutoencoderTrainer(model)
+	order := make([]int, len(inputs))
+	for i := range order {
+		order[i] = i
+	}
+	for epoch := 0; epoch < options.Epochs; epoch++ {
+		random.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+
+		var loss float64
+		for start := 0; start < len(order); start += options.BatchSize {
+			end := min(start+options.BatchSize, len(order))
+			batch := make([][]float64, 0, end-start)
+			for _, index := range order[start:end] {
+				batch = append(batch, inputs[index])
+			}
+			loss += trainer.step(batch) * float64(len(batch))
+		}
+		model.Losses = append(model.Losses, loss/float64(len(inputs)))
+	}
+
+	if err := model.Calibrate(normal, 0.01); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// autoencoderTrainer menyimpan gradient dan state optimizer selama training
+type autoencoderTrainer struct {
+	model       *Autoencoder
+	gradWeights [][][]float64
+	gradBias    [][]float64
+
+	// Moment pertama dan kedua untuk Adam
+	firstWeights, secondWeights [][][]float64
+	firstBias, secondBias       [][]float64
+	steps                       int // jumlah update, untuk koreksi bias Adam
+}
+
+func newAutoencoderTrainer(model *Autoencoder) *autoencoderTrainer {
+	zeros := func() ([][][]float64, [][]float64) {
+		weights := make([][][]float64, len(model.Layers))
+		bias := make([][]float64, len(model.Layers))
+		for l, layer := range model.Layers {
+			weights[l] = make([][]float64, len(layer.Weights))
+			for i := range layer.Weights {
+				weights[l][i] = make([]float64, len(layer.Weights[i]))
+			}
+			bias[l] = make([]float64, len(layer.Bias))
+		}
+		return weights, bias
+	}
+
+	trainer := &autoencoderTrainer{model: model}
+	trainer.gradWeights, trainer.gradBias = zeros()
+	trainer.firstWeights, trainer.firstBias = zeros()
+	trainer.secondWeights, trainer.secondBias = zeros()
+	return trainer
+}
+
+// step menjalankan satu mini-batch dan mengembalikan rata-rata loss sebelum update
+func (t *autoencoderTrainer) step(batch [][]float64) float64 {
+	loss := t.backward(batch)
+	t.apply(1 / float64(len(batch)))
+	return loss
+}
+
+// backward menghitung jumlah gradient seluruh batch dan mengembalikan rata-rata loss
+func (t *autoencoderTrainer) backward(batch [][]float64) float64 {
+	layers := t.model.Layers
+	for l := range layers {
+		for i := range t.gradWeights[l] {
+			clear(t.gradWeights[l][i])
+		}
+		clear(t.gradBias[l])
+	}
+
+	var loss float64
+	for _, input := range batch {
+		activations := [][]float64{input}
+		for _, layer := range layers {
+			activations = append(activations, layer.forward(activations[len(activations)-1]))
+		}
+
+		// Loss adalah mean squared error, delta = turunan loss terhadap nilai sebelum aktivasi
+		output := activations[len(activations)-1]
+		delta := make([]float64, len(output))
+		for i := range output {
+			diff := output[i] - input[i]
+			loss += diff * diff / float64(len(output))
+			delta[i] = 2 * diff / float64(len(output))
+		}
+
+		for l := len(layers) - 1; l >= 0; l-- {
+			previous := activations[l]
+			for i := range delta {
+				for j := range previous {
+					t.gradWeights[l][i][j] += delta[i] * previous[j]
+				}
+				t.gradBias[l][i] += delta[i]
+			}
+			if l == 0 {
+				break
+			}
+
+			next := make([]float64, len(previous))
+			for j := range previous {
+				var sum float64
+				for i := range delta {
+					sum += layers[l].Weights[i][j] * delta[i]
+				}
+				next[j] = sum * (1 - previous[j]*previous[j]) // turunan tanh
+			}
+			delta = next
+		}
+	}
+
+	return loss / float64(len(batch))
+}
+
+// apply mengupdate bobot dengan gradient hasil backward dikali scale
+func (t *autoencoderTrainer) apply(scale float64) {
+	t.steps++
+	options := t.model.Options
+	for l, layer := range t.model.Layers {
+		for i := range layer.Weights {
+			for j := range layer.Weights[i] {
+				layer.Weights[i][j] -= t.update(options, t.gradWeights[l][i][j]*scale, &t.firstWeights[l][i][j], &t.secondWeights[l][i][j])
+			}
+			layer.Bias[i] -= t.update(options, t.gradBias[l][i]*scale, &t.firstBias[l][i], &t.secondBias[l][i])
+		}
+	}
+}
+
+func (t *autoencoderTrainer) update(options AutoencoderOptions, gradient float64, first, second *float64) float64 {
+	if options.Optimizer == OptimizerSGD {
+		return options.LearningRate * gradient
+	}
+
+	const beta1, beta2, epsilon = 0.9, 0.999, 1e-8
+	*first = beta1**first + (1-beta1)*gradient
+	*second = beta2**second + (1-beta2)*gradient*gradient
+	correctedFirst := *first / (1 - math.Pow(beta1, float64(t.steps)))
+	correctedSecond := *second / (1 - math.Pow(beta2, float64(t.steps)))
+	return options.LearningRate * correctedFirst / (math.Sqrt(correctedSecond) + epsilon)
+}
+
+// Reconstruct mengembalikan hasil rekonstruksi data dalam skala asli
+func (a *Autoencoder) Reconstruct(data HasValueCount) []float64 {
+	output := a.reconstructScaled(a.Scaler.Transform(data))
+	for i := range output {
+		output[i] = output[i]*a.Scaler.StdDev[i] + a.Scaler.Mean[i]
+	}
+	return output
+}
+
+func (a *Autoencoder) reconstructScaled(input []float64) []float64 {
+	output := input
+	for _, layer := range a.Layers {
+		output = layer.forward(output)
+	}
+	return output
+}
+
+// Score adalah reconstruction error (mean squared error pada feature yang sudah diskalakan)
+func (a *Autoencoder) Score(data HasValueCount) float64 {
+	input := a.Scaler.Transform(data)
+	output := a.reconstructScaled(input)
+
+	var sum float64
+	for i := range input {
+		diff := output[i] - input[i]
+		sum += diff * diff
+	}
+	return sum / float64(len(input))
+}
+
+// Predict mengembalikan true jika reconstruction error melewati Threshold
+func (a *Autoencoder) Predict(data FeatureProvider) bool {
+	return a.Score(data) > a.Threshold
+}
+
+// Calibrate memilih Threshold sehingga sekitar falsePositiveRate bagian dari data normal dianggap anomali.
+// TrainAutoencoder sudah memanggilnya dengan 1% data latih
+func (a *Autoencoder) Calibrate(normal DataSet, falsePositiveRate float64) error {
+	threshold, err := ScoreThreshold(a, normal, falsePositiveRate)
+	if err != nil {
+		return err
+	}
+	a.Threshold = threshold
+	return nil
+}
+
+// SaveModel menyimpan autoencoder ke file JSON
+func (a *Autoencoder) SaveModel(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	return encoder.Encode(a)
+}
+
+// LoadAutoencoder membaca autoencoder dari file JSON
+func LoadAutoencoder(filename string) (*Autoencoder, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var model Autoencoder
+	if err := json.NewDecoder(file).Decode(&model); err != nil {
+		return nil, err
+	}
+	if model.Scaler == nil || len(model.Layers) == 0 {
+		return nil, fmt.Errorf("autoencoder %s has no layers", filename)
+	}
+	return &model, nil
+}
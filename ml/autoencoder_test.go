@@ -0,0 +1,159 @@
+package ml
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// autoencoderData membuat data normal dengan tiga feature yang saling berkaitan,
+// sehingga bisa direkonstruksi dari bottleneck yang lebih kecil
+func autoencoderData(count int) DataSet {
+	random := rand.New(rand.NewSource(7))
+	dataset := make(DataSet, count)
+	for i := range dataset {
+		x := random.Intn(100)
+		dataset[i] = WindowSample{Record: Record{
+			Names:  []string{"a", "b", "c"},
+			Values: []int{x, 2*x + random.Intn(5), 300 - x + random.Intn(5)},
+		}}
+	}
+	return dataset
+}
+
+func smallAutoencoderOptions(optimizer Optimizer) AutoencoderOptions {
+	options := DefaultAutoencoderOptions()
+	options.Epochs = 30
+	options.Optimizer = optimizer
+	if optimizer == OptimizerSGD {
+		options.LearningRate = 0.05
+	}
+	return options
+}
+
+func TestAutoencoderSameSeedSameModel(t *testing.T) {
+	dataset := autoencoderData(200)
+	first, err := TrainAutoencoder(dataset, smallAutoencoderOptions(OptimizerAdam))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := TrainAutoencoder(dataset, smallAutoencoderOptions(OptimizerAdam))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(first.Layers, second.Layers) || first.Threshold != second.Threshold {
+		t.Error("same seed produced different models")
+	}
+
+	options := smallAutoencoderOptions(OptimizerAdam)
+	options.Seed = 2
+	third, err := TrainAutoencoder(dataset, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(first.Layers, third.Layers) {
+		t.Error("different seed produced the same model")
+	}
+}
+
+func TestAutoencoderLossDecreases(t *testing.T) {
+	dataset := autoencoderData(200)
+	for _, optimizer := range []Optimizer{OptimizerSGD, OptimizerAdam} {
+		model, err := TrainAutoencoder(dataset, smallAutoencoderOptions(optimizer))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(model.Losses) != 30 {
+			t.Fatalf("%s: %d losses, want one per epoch", optimizer, len(model.Losses))
+		}
+		first, last := model.Losses[0], model.Losses[len(model.Losses)-1]
+		if last >= first/2 {
+			t.Errorf("%s: loss went from %.4f to %.4f", optimizer, first, last)
+		}
+
+		// Data yang tidak mengikuti hubungan antar feature sulit direkonstruksi
+		anomaly := WindowSample{Record: Record{Names: []string{"a", "b", "c"}, Values: []int{50, 0, 0}}}
+		if !model.Predict(anomaly) {
+			t.Errorf("%s: anomaly scored %.4f, threshold %.4f", optimizer, model.Score(anomaly), model.Threshold)
+		}
+	}
+}
+
+func TestAutoencoderSaveLoad(t *testing.T) {
+	dataset := autoencoderData(100)
+	model, err := TrainAutoencoder(dataset, smallAutoencoderOptions(OptimizerAdam))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "autoencoder.json")
+	if err := model.SaveModel(filename); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadAutoencoder(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Threshold != model.Threshold {
+		t.Errorf("threshold %v, want %v", loaded.Threshold, model.Threshold)
+	}
+	for _, data := range dataset {
+		if got, want := loaded.Score(data), model.Score(data); got != want {
+			t.Fatalf("loaded model scores %v, want %v", got, want)
+		}
+	}
+}
+
+// Gradient dari backward harus sama dengan turunan numerik loss
+func TestAutoencoderGradient(t *testing.T) {
+	options := smallAutoencoderOptions(OptimizerSGD)
+	options.Epochs = 1
+	model, err := TrainAutoencoder(autoencoderData(20), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch := [][]float64{{0.5, -1, 0.2}, {-0.3, 0.8, 1.1}, {1.2, 0.1, -0.7}}
+	loss := func() float64 {
+		var sum float64
+		for _, input := range batch {
+			output := model.reconstructScaled(input)
+			for i := range input {
+				sum += (output[i] - input[i]) * (output[i] - input[i]) / float64(len(input))
+			}
+		}
+		return sum / float64(len(batch))
+	}
+
+	trainer := newAutoencoderTrainer(model)
+	if got, want := trainer.backward(batch), loss(); math.Abs(got-want) > 1e-12 {
+		t.Fatalf("backward loss %v, want %v", got, want)
+	}
+
+	const h = 1e-6
+	check := func(name string, parameter *float64, gradient float64) {
+		original := *parameter
+		*parameter = original + h
+		plus := loss()
+		*parameter = original - h
+		minus := loss()
+		*parameter = original
+
+		numeric := (plus - minus) / (2 * h)
+		analytic := gradient / float64(len(batch))
+		if math.Abs(numeric-analytic) > 1e-6*max(1, math.Abs(numeric)) {
+			t.Errorf("%s: gradient %v, numeric %v", name, analytic, numeric)
+		}
+	}
+	for l, layer := range model.Layers {
+		for i := range layer.Weights {
+			for j := range layer.Weights[i] {
+				check("weight", &layer.Weights[i][j], trainer.gradWeights[l][i][j])
+			}
+			check("bias", &layer.Bias[i], trainer.gradBias[l][i])
+		}
+	}
+}
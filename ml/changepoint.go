@@ -0,0 +1,335 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+func init() {
+	RegisterComparator("changepoint", func() any { return NewChangePointDetector() })
+}
+
+// ChangePointPrior adalah prior Normal-Gamma satu feature.
+// StdDev adalah noise di dalam satu regime, bukan sebaran seluruh data
+type ChangePointPrior struct {
+	Mean   float64
+	StdDev float64
+}
+
+// Statistik cukup Normal-Gamma untuk satu panjang run
+type normalGamma struct {
+	Mu    float64
+	Kappa float64
+	Alpha float64
+	Beta  float64
+}
+
+// Update posterior dengan satu observasi
+func (ng normalGamma) update(x float64) normalGamma {
+	return normalGamma{
+		Mu:    (ng.Kappa*ng.Mu + x) / (ng.Kappa + 1),
+		Kappa: ng.Kappa + 1,
+		Alpha: ng.Alpha + 0.5,
+		Beta:  ng.Beta + ng.Kappa*(x-ng.Mu)*(x-ng.Mu)/(2*(ng.Kappa+1)),
+	}
+}
+
+// Log density prediktif (Student-t) dari observasi berikutnya
+func (ng normalGamma) logPredictive(x float64) float64 {
+	nu := 2 * ng.Alpha
+	scale2 := ng.Beta * (ng.Kappa + 1) / (ng.Alpha * ng.Kappa)
+	a, _ := math.Lgamma((nu + 1) / 2)
+	b, _ := math.Lgamma(nu / 2)
+	d := (x - ng.Mu) * (x - ng.Mu) / (nu * scale2)
+	return a - b - 0.5*math.Log(nu*math.Pi*scale2) - (nu+1)/2*math.Log1p(d)
+}
+
+// ChangePointResult adalah hasil deteksi change point untuk satu sampel
+type ChangePointResult struct {
+	Timestamp   float64
+	Probability float64 // peluang regime baru dimulai dalam Lag sampel terakhir
+	RunLength   int     // panjang run yang paling mungkin (MAP)
+	IsChange    bool    // Probability melewati Threshold
+}
+
+// ChangePointDetector adalah Bayesian online change point detection (Adams & MacKay)
+// dengan model Normal-Gamma untuk setiap feature dan panjang run yang sama untuk semua feature.
+// Bisa dipakai sendiri lewat Update, atau sebagai RecordComparator di WindowDetector.
+// Detector ini menyimpan state, buat instance baru untuk setiap stream
+type ChangePointDetector struct {
+	Features     []string
+	Hazard       float64 // peluang change point di setiap sampel, 1 / panjang regime rata-rata
+	MaxRunLength int     // run lebih panjang digabung agar memori tetap
+	Lag          int     // jumlah sampel terakhir yang dijumlahkan untuk Probability
+	Threshold    float64 // batas IsChange
+
+	Priors map[string]ChangePointPrior
+
+	// State, ikut tersimpan di snapshot
+	LogRunProbs []float64
+	Stats       [][]normalGamma
+	Count       int
+}
+
+// NewChangePointDetector membuat detector dengan hazard 1/100 untuk rpm dan speed
+func NewChangePointDetector() *ChangePointDetector {
+	return &ChangePointDetector{
+		Features:     []string{"rpm", "speed"},
+		Hazard:       0.01,
+		MaxRunLength: 300,
+		Lag:          3,
+		Threshold:    0.5,
+		Priors:       make(map[string]ChangePointPrior),
+	}
+}
+
+// Calibrate mengisi prior dari data normal. Noise di dalam regime diperkirakan dari
+// selisih sampel berurutan sehingga perpindahan regime di data kalibrasi tidak ikut terhitung
+func (d *ChangePointDetector) Calibrate(sequence []SequentialProvider) error {
+	if len(sequence) < 2 {
+		return fmt.Errorf("calibration needs at least 2 samples, got %d", len(sequence))
+	}
+
+	priors := make(map[string]ChangePointPrior)
+	for _, feature := range d.Features {
+		values := make([]float64, 0, len(sequence))
+		diffs := make([]float64, 0, len(sequence)-1)
+		for i, data := range sequence {
+			value, exists := FeatureValueByName(data, feature)
+			if !exists {
+				return fmt.Errorf("feature not found in data: %s", feature)
+			}
+			values = append(values, value)
+			if i > 0 {
+				diffs = append(diffs, value-values[i-1])
+			}
+		}
+
+		mean, _ := meanStdDev(values)
+		_, diffStdDev := meanStdDev(diffs)
+		priors[feature] = ChangePointPrior{Mean: mean, StdDev: math.Max(diffStdDev/math.Sqrt2, 1)}
+	}
+
+	d.Priors = priors
+	d.Reset()
+	return nil
+}
+
+// Reset menghapus state sehingga sampel berikutnya dianggap awal regime
+func (d *ChangePointDetector) Reset() {
+	d.LogRunProbs = nil
+	d.Stats = nil
+	d.Count = 0
+}
+
+// Prior awal satu feature. Feature tanpa prior memakai nilai pertama yang dilihat
+func (d *ChangePointDetector) prior(feature string, first float64) normalGamma {
+	prior, exists := d.Priors[feature]
+	if !exists {
+		prior = ChangePointPrior{Mean: first, StdDev: math.Max(math.Abs(first)*0.1, 1)}
+		if d.Priors == nil {
+			d.Priors = make(map[string]ChangePointPrior)
+		}
+		d.Priors[feature] = prior
+	}
+
+	// Alpha 2 membuat variansi yang diharapkan sama dengan StdDev²
+	return normalGamma{Mu: prior.Mean, Kappa: 0.1, Alpha: 2, Beta: prior.StdDev * prior.StdDev}
+}
+
+// Update memproses satu sampel dan mengembalikan peluang change point
+func (d *ChangePointDetector) Update(data SequentialProvider) (ChangePointResult, error) {
+	values := make([]float64, len(d.Features))
+	for i, feature := range d.Features {
+		value, exists := FeatureValueByName(data, feature)
+		if !exists {
+			return ChangePointResult{}, fmt.Errorf("feature not found in data: %s", feature)
+		}
+		values[i] = value
+	}
+
+	priors := make([]normalGamma, len(d.Features))
+	for i, feature := range d.Features {
+		priors[i] = d.prior(feature, values[i])
+	}
+
+	if len(d.LogRunProbs) == 0 || len(d.Stats) != len(d.LogRunProbs) {
+		d.LogRunProbs = []float64{0}
+		d.Stats = [][]normalGamma{priors}
+	}
+
+	hazard := d.Hazard
+	if hazard <= 0 || hazard >= 1 {
+		hazard = 0.01
+	}
+	logHazard, logSurvive := math.Log(hazard), math.Log(1-hazard)
+
+	// Peluang setiap panjang run dikali likelihood prediktif
+	n := len(d.LogRunProbs)
+	logJoint := make([]float64, n)
+	for r := 0; r < n; r++ {
+		logJoint[r] = d.LogRunProbs[r]
+		for i, value := range values {
+			logJoint[r] += d.Stats[r][i].logPredictive(value)
+		}
+	}
+
+	next := make([]float64, n+1)
+	next[0] = logSumExp(logJoint) + logHazard
+	for r := 0; r < n; r++ {
+		next[r+1] = logJoint[r] + logSurvive
+	}
+
+	nextStats := make([][]normalGamma, n+1)
+	nextStats[0] = priors
+	for r := 0; r < n; r++ {
+		nextStats[r+1] = make([]normalGamma, len(values))
+		for i, value := range values {
+			nextStats[r+1][i] = d.Stats[r][i].update(value)
+		}
+	}
+
+	// Normalisasi
+	total := logSumExp(next)
+	for r := range next {
+		next[r] -= total
+	}
+
+	// Run terpanjang digabung agar ukuran state tetap
+	maxRun := d.MaxRunLength
+	if maxRun <= 0 {
+		maxRun = 300
+	}
+	if len(next) > maxRun+1 {
+		next[maxRun] = logSumExp(next[maxRun:])
+		next = next[:maxRun+1]
+		nextStats = nextStats[:maxRun+1]
+	}
+
+	d.LogRunProbs = next
+	d.Stats = nextStats
+	d.Count++
+
+	lag := max(d.Lag, 1)
+	result := ChangePointResult{Timestamp: data.GetTimestamp()}
+	best := math.Inf(-1)
+	for r, logProb := range next {
+		if r < lag {
+			result.Probability += math.Exp(logProb)
+		}
+		if logProb > best {
+			best = logProb
+			result.RunLength = r
+		}
+	}
+
+	// Awal stream selalu terlihat seperti run pendek
+	result.IsChange = d.Count > lag && result.Probability > d.Threshold
+	return result, nil
+}
+
+// CompareRecords menjalankan Update dan mengembalikan Probability sebagai skor.
+// Threshold FeatureConfig menentukan kapan change point dilaporkan
+func (d *ChangePointDetector) CompareRecords(prev, current SequentialProvider, window []SequentialProvider) (float64, string) {
+	score, reason, err := d.CompareRecordsWithError(prev, current, window)
+	if err != nil {
+		return 0, ""
+	}
+	return score, reason
+}
+
+// CompareRecordsWithError sama dengan CompareRecords, tetapi mengembalikan error jika Update gagal
+// (misalnya feature tidak ada di data) agar WindowDetector bisa mencatatnya di FeatureResult.Error
+func (d *ChangePointDetector) CompareRecordsWithError(prev, current SequentialProvider, window []SequentialProvider) (float64, string, error) {
+	previousRun := 0
+	if len(d.LogRunProbs) > 0 {
+		previousRun = argMax(d.LogRunProbs)
+	}
+
+	result, err := d.Update(current)
+	if err != nil {
+		return 0, "", fmt.Errorf("change point detector: %w", err)
+	}
+	if d.Count <= max(d.Lag, 1) {
+		return 0, "", nil
+	}
+
+	return result.Probability, fmt.Sprintf("regime change in %s (probability %.2f, previous run %d samples)",
+		strings.Join(d.Features, ", "), result.Probability, previousRun), nil
+}
+
+// DetectChangePoints menjalankan detector baru di atas seluruh urutan data.
+// Hasilnya satu ChangePointResult per sampel
+func (d *ChangePointDetector) DetectChangePoints(sequence []SequentialProvider) ([]ChangePointResult, error) {
+	d.Reset()
+
+	results := make([]ChangePointResult, 0, len(sequence))
+	for _, data := range sequence {
+		result, err := d.Update(data)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Segments membagi hasil DetectChangePoints menjadi regime [awal, akhir) berdasarkan index sampel.
+// Satu change point ditandai di sampel tempat run yang paling mungkin dimulai
+func Segments(results []ChangePointResult) [][2]int {
+	if len(results) == 0 {
+		return nil
+	}
+
+	starts := map[int]bool{0: true}
+	for i, result := range results {
+		if result.IsChange {
+			// RunLength menghitung sampel di run saat ini, termasuk sampel ini
+			starts[min(max(i-result.RunLength+1, 0), i)] = true
+		}
+	}
+
+	indexes := make([]int, 0, len(starts))
+	for start := range starts {
+		indexes = append(indexes, start)
+	}
+	sort.Ints(indexes)
+
+	segments := make([][2]int, 0, len(indexes))
+	for i, start := range indexes {
+		end := len(results)
+		if i+1 < len(indexes) {
+			end = indexes[i+1]
+		}
+		segments = append(segments, [2]int{start, end})
+	}
+	return segments
+}
+
+func logSumExp(values []float64) float64 {
+	best := math.Inf(-1)
+	for _, v := range values {
+		best = math.Max(best, v)
+	}
+	if math.IsInf(best, -1) {
+		return best
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += math.Exp(v - best)
+	}
+	return best + math.Log(sum)
+}
+
+func argMax(values []float64) int {
+	best := 0
+	for i, v := range values {
+		if v > values[best] {
+			best = i
+		}
+	}
+	return best
+}
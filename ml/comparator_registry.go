@@ -0,0 +1,216 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ComparatorFactory membuat instance baru comparator dengan nilai default.
+// Hasilnya bisa FeatureComparator, RecordComparator atau WindowComparator
+type ComparatorFactory func() any
+
+type comparatorEntry struct {
+	name    string
+	typ     reflect.Type
+	factory ComparatorFactory
+}
+
+// Registry global nama comparator, dipakai untuk snapshot dan konfigurasi
+var comparatorRegistry = struct {
+	sync.RWMutex
+	byName map[string]comparatorEntry
+	byType map[reflect.Type]string
+}{
+	byName: make(map[string]comparatorEntry),
+	byType: make(map[reflect.Type]string),
+}
+
+func init() {
+	RegisterComparator("default", func() any { return DefaultComparator{} })
+	RegisterComparator("stat", func() any { return StatComparator{} })
+	RegisterComparator("ewma", func() any { return &EWMAComparator{Lambda: 0.2} })
+	RegisterComparator("cusum", func() any { return &CUSUMComparator{Slack: 0.5} })
+}
+
+// RegisterComparator mendaftarkan comparator dengan nama tertentu.
+// Field exported dari comparator menjadi parameter-nya (format JSON)
+func RegisterComparator(name string, factory ComparatorFactory) {
+	prototype := factory()
+	if prototype == nil {
+		panic(fmt.Sprintf("comparator factory %q returned nil", name))
+	}
+
+	comparatorRegistry.Lock()
+	defer comparatorRegistry.Unlock()
+
+	typ := reflect.TypeOf(prototype)
+	comparatorRegistry.byName[name] = comparatorEntry{name: name, typ: typ, factory: factory}
+	comparatorRegistry.byType[typ] = name
+}
+
+// ComparatorName mengembalikan nama terdaftar dari sebuah comparator
+func ComparatorName(comparator any) (string, bool) {
+	comparatorRegistry.RLock()
+	defer comparatorRegistry.RUnlock()
+
+	name, exists := comparatorRegistry.byType[reflect.TypeOf(comparator)]
+	return name, exists
+}
+
+// RegisteredComparators mengembalikan semua nama comparator yang terdaftar
+func RegisteredComparators() []string {
+	comparatorRegistry.RLock()
+	defer comparatorRegistry.RUnlock()
+
+	names := make([]string, 0, len(comparatorRegistry.byName))
+	for name := range comparatorRegistry.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateComparatorType memastikan nama comparator tidak kosong dan terdaftar
+func validateComparatorType(name string) error {
+	if name == "" {
+		return fmt.Errorf("comparator has no type")
+	}
+
+	comparatorRegistry.RLock()
+	_, exists := comparatorRegistry.byName[name]
+	comparatorRegistry.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("unknown comparator: %s", name)
+	}
+	return nil
+}
+
+// TrainedComparator adalah comparator yang berisi model hasil training, misalnya HMM.
+// NewComparator menolak konfigurasi yang tidak menyertakan hasil training-nya
+type TrainedComparator interface {
+	CheckTrained() error
+}
+
+// NewComparator membuat comparator dari nama dan parameter JSON (boleh kosong).
+// Parameter menimpa nilai default dari factory, map di parameter menggantikan seluruh map default
+func NewComparator(name string, params json.RawMessage) (any, error) {
+	comparatorRegistry.RLock()
+	entry, exists := comparatorRegistry.byName[name]
+	comparatorRegistry.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unknown comparator: %s", name)
+	}
+
+	comparator := entry.factory()
+	if len(params) > 0 && string(params) != "null" {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(params, &fields); err != nil {
+			return nil, fmt.Errorf("invalid params for comparator %s: %v", name, err)
+		}
+
+		// Decode parameter di atas nilai default dari factory
+		target := reflect.New(entry.typ)
+		target.Elem().Set(reflect.ValueOf(comparator))
+		clearMapParams(target.Elem(), fields)
+		if err := json.Unmarshal(params, target.Interface()); err != nil {
+			return nil, fmt.Errorf("invalid params for comparator %s: %v", name, err)
+		}
+		comparator = target.Elem().Interface()
+	}
+
+	if trained, ok := comparator.(TrainedComparator); ok {
+		if err := trained.CheckTrained(); err != nil {
+			return nil, fmt.Errorf("comparator %s: %v", name, err)
+		}
+	}
+	return comparator, nil
+}
+
+// clearMapParams mengosongkan field map yang ada di parameter, termasuk field dari struct embedded,
+// karena json.Unmarshal menggabungkan isi map dengan map yang sudah ada
+func clearMapParams(value reflect.Value, fields map[string]json.RawMessage) {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Anonymous {
+			clearMapParams(value.Field(i), fields)
+			continue
+		}
+		if !field.IsExported() || field.Type.Kind() != reflect.Map {
+			continue
+		}
+
+		name := field.Name
+		if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" {
+			name = tag
+		}
+		for key := range fields {
+			if strings.EqualFold(key, name) {
+				value.Field(i).SetZero()
+			}
+		}
+	}
+}
+
+// ComparatorParams mengembalikan parameter comparator dalam format JSON
+func ComparatorParams(comparator any) (json.RawMessage, error) {
+	params, err := json.Marshal(comparator)
+	if err != nil {
+		return nil, err
+	}
+	if string(params) == "{}" || string(params) == "null" {
+		return nil, nil
+	}
+	return params, nil
+}
+
+func newFeatureComparator(name string, params json.RawMessage) (FeatureComparator, error) {
+	comparator, err := NewComparator(name, params)
+	if err != nil {
+		return nil, err
+	}
+	fc, ok := comparator.(FeatureComparator)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a FeatureComparator", name)
+	}
+	return fc, nil
+}
+
+func newRecordComparator(name string, params json.RawMessage) (RecordComparator, error) {
+	comparator, err := NewComparator(name, params)
+	if err != nil {
+		return nil, err
+	}
+	rc, ok := comparator.(RecordComparator)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a RecordComparator", name)
+	}
+	return rc, nil
+}
+
+func newWindowComparator(name string, params json.RawMessage) (WindowComparator, error) {
+	comparator, err := NewComparator(name, params)
+	if err != nil {
+		return nil, err
+	}
+	wc, ok := comparator.(WindowComparator)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a WindowComparator", name)
+	}
+	return wc, nil
+}
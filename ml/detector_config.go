@@ -0,0 +1,204 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ComparatorDefinition memilih comparator dari registry beserta parameternya
+type ComparatorDefinition struct {
+	Type   string          `json:"type"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// WindowCheckDefinition adalah definisi satu WindowCheck di file konfigurasi
+type WindowCheckDefinition struct {
+	Threshold  float64              `json:"threshold"`
+	Comparator ComparatorDefinition `json:"comparator"`
+}
+
+// FeatureDefinition adalah definisi satu feature di file konfigurasi
+type FeatureDefinition struct {
+	Name         string                  `json:"name"`
+	Threshold    float64                 `json:"threshold"`
+	Comparator   *ComparatorDefinition   `json:"comparator,omitempty"`
+	WindowChecks []WindowCheckDefinition `json:"windowChecks,omitempty"`
+}
+
+// DetectorConfig adalah konfigurasi WindowDetector yang bisa ditulis di file JSON
+type DetectorConfig struct {
+	WindowSize      int                 `json:"windowSize"`
+	CheckTimestamps bool                `json:"checkTimestamps,omitempty"`
+	MaxGap          float64             `json:"maxGap,omitempty"`
+	Features        []FeatureDefinition `json:"features"`
+}
+
+// Validate mengecek konfigurasi sebelum dipakai
+func (c *DetectorConfig) Validate() error {
+	if c.WindowSize < 2 {
+		return fmt.Errorf("windowSize must be at least 2, got %d", c.WindowSize)
+	}
+	if c.MaxGap < 0 {
+		return fmt.Errorf("maxGap must not be negative")
+	}
+
+	names := make(map[string]bool)
+	for i, feature := range c.Features {
+		if feature.Name == "" {
+			return fmt.Errorf("feature %d has no name", i)
+		}
+		if names[feature.Name] {
+			return fmt.Errorf("feature %s is defined more than once", feature.Name)
+		}
+		names[feature.Name] = true
+
+		if feature.Comparator != nil {
+			if err := validateComparatorType(feature.Comparator.Type); err != nil {
+				return fmt.Errorf("feature %s: %v", feature.Name, err)
+			}
+		}
+		for _, check := range feature.WindowChecks {
+			if err := validateComparatorType(check.Comparator.Type); err != nil {
+				return fmt.Errorf("feature %s: window check: %v", feature.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// NewDetector membuat WindowDetector dari konfigurasi.
+// Comparator yang mengimplementasikan RecordComparator dipakai sebagai RecordComparator,
+// selain itu sebagai FeatureComparator
+func (c *DetectorConfig) NewDetector() (*WindowDetector, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	wd := NewWindowDetector(c.WindowSize)
+	wd.CheckTimestamps = c.CheckTimestamps
+	wd.MaxGap = c.MaxGap
+
+	for _, feature := range c.Features {
+		config := FeatureConfig{
+			Name:      feature.Name,
+			Threshold: feature.Threshold,
+		}
+
+		if feature.Comparator != nil {
+			comparator, err := NewComparator(feature.Comparator.Type, feature.Comparator.Params)
+			if err != nil {
+				return nil, fmt.Errorf("feature %s: %v", feature.Name, err)
+			}
+
+			switch cmp := comparator.(type) {
+			case RecordComparator:
+				config.RecordComparator = cmp
+			case FeatureComparator:
+				config.Comparator = cmp
+			default:
+				return nil, fmt.Errorf("feature %s: %s is not a feature or record comparator", feature.Name, feature.Comparator.Type)
+			}
+		}
+
+		for _, check := range feature.WindowChecks {
+			comparator, err := newWindowComparator(check.Comparator.Type, check.Comparator.Params)
+			if err != nil {
+				return nil, fmt.Errorf("feature %s: %v", feature.Name, err)
+			}
+			config.WindowChecks = append(config.WindowChecks, WindowCheck{
+				Threshold:  check.Threshold,
+				Comparator: comparator,
+			})
+		}
+
+		if err := wd.AddFeatureConfig(config); err != nil {
+			return nil, err
+		}
+	}
+
+	return wd, nil
+}
+
+// Config mengembalikan konfigurasi detector saat ini, berguna sebagai titik awal kalibrasi.
+// Semua comparator harus terdaftar di registry
+func (wd *WindowDetector) Config() (*DetectorConfig, error) {
+	snapshot, err := wd.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &DetectorConfig{
+		WindowSize:      snapshot.WindowSize,
+		CheckTimestamps: snapshot.CheckTimestamps,
+		MaxGap:          snapshot.MaxGap,
+	}
+
+	for _, feature := range snapshot.Features {
+		definition := FeatureDefinition{
+			Name:      feature.Name,
+			Threshold: feature.Threshold,
+		}
+
+		// Record comparator diutamakan, sama seperti saat detectAnomaly
+		comparator := feature.RecordComparator
+		if comparator == nil {
+			comparator = feature.Comparator
+		}
+		if comparator != nil {
+			definition.Comparator = &ComparatorDefinition{Type: comparator.Type, Params: comparator.Params}
+		}
+
+		for _, check := range feature.WindowChecks {
+			definition.WindowChecks = append(definition.WindowChecks, WindowCheckDefinition{
+				Threshold:  check.Threshold,
+				Comparator: ComparatorDefinition{Type: check.Comparator.Type, Params: check.Comparator.Params},
+			})
+		}
+
+		config.Features = append(config.Features, definition)
+	}
+
+	return config, nil
+}
+
+// SaveDetectorConfig menyimpan konfigurasi ke file JSON
+func (c *DetectorConfig) SaveDetectorConfig(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(c)
+}
+
+// LoadDetectorConfig membaca konfigurasi dari file JSON
+func LoadDetectorConfig(filename string) (*DetectorConfig, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var config DetectorConfig
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("invalid detector config %s: %v", filename, err)
+	}
+
+	return &config, nil
+}
+
+// LoadDetectorFromConfig membaca file konfigurasi dan langsung membuat WindowDetector
+func LoadDetectorFromConfig(filename string) (*WindowDetector, error) {
+	config, err := LoadDetectorConfig(filename)
+	if err != nil {
+		return nil, err
+	}
+	return config.NewDetector()
+}
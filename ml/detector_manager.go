@@ -0,0 +1,210 @@
+package ml
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StreamKey mengidentifikasi satu stream data, misalnya satu ECU di satu kendaraan
+type StreamKey struct {
+	VehicleID string
+	ECUID     string
+}
+
+func (k StreamKey) String() string {
+	if k.ECUID == "" {
+		return k.VehicleID
+	}
+	return fmt.Sprintf("%s/%s", k.VehicleID, k.ECUID)
+}
+
+// DetectorFactory membuat detector baru untuk stream yang belum pernah dilihat
+type DetectorFactory func(key StreamKey) SequentialDetector
+
+// Satu stream dengan lock sendiri agar stream berbeda bisa diproses paralel
+type detectorStream struct {
+	mu       sync.Mutex
+	detector SequentialDetector
+	lastSeen time.Time
+}
+
+// DetectorManager meneruskan data ke detector per stream dan aman dipakai bersamaan.
+// Stream yang tidak menerima data lebih lama dari TTL akan dihapus, dan jumlah stream
+// dibatasi MaxStreams (stream yang paling lama tidak dipakai dihapus lebih dulu).
+// Karena setiap WindowDetector menyimpan paling banyak WindowSize record,
+// total memori dibatasi MaxStreams * WindowSize record
+type DetectorManager struct {
+	mu         sync.Mutex
+	factory    DetectorFactory
+	streams    map[StreamKey]*detectorStream
+	TTL        time.Duration // 0 berarti stream tidak pernah kadaluarsa
+	MaxStreams int           // 0 berarti tidak dibatasi
+
+	now func() time.Time
+}
+
+// NewDetectorManager membuat instance baru DetectorManager
+func NewDetectorManager(factory DetectorFactory, ttl time.Duration, maxStreams int) *DetectorManager {
+	return &DetectorManager{
+		factory:    factory,
+		streams:    make(map[StreamKey]*detectorStream),
+		TTL:        ttl,
+		MaxStreams: maxStreams,
+		now:        time.Now,
+	}
+}
+
+// AddData meneruskan data ke detector milik stream key
+func (m *DetectorManager) AddData(key StreamKey, data SequentialProvider) (*AnomalyReport, error) {
+	stream, err := m.getStream(key)
+	if err != nil {
+		return nil, err
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	return stream.detector.AddDataWithReport(data)
+}
+
+// Ambil stream yang ada atau buat baru, sekaligus perbarui lastSeen
+func (m *DetectorManager) getStream(key StreamKey) (*detectorStream, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+
+	stream, exists := m.streams[key]
+	if exists && m.TTL > 0 && now.Sub(stream.lastSeen) > m.TTL {
+		// Stream sudah kadaluarsa, mulai dari awal
+		delete(m.streams, key)
+		exists = false
+	}
+
+	if !exists {
+		if m.MaxStreams > 0 && len(m.streams) >= m.MaxStreams {
+			m.evictIdleLocked(now)
+			for len(m.streams) >= m.MaxStreams {
+				m.evictOldestLocked()
+			}
+		}
+
+		detector := m.factory(key)
+		if detector == nil {
+			return nil, fmt.Errorf("factory returned no detector for stream %s", key)
+		}
+
+		stream = &detectorStream{detector: detector}
+		m.streams[key] = stream
+	}
+
+	stream.lastSeen = now
+	return stream, nil
+}
+
+// EvictIdle menghapus stream yang tidak menerima data lebih lama dari TTL.
+// Mengembalikan jumlah stream yang dihapus
+func (m *DetectorManager) EvictIdle() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.evictIdleLocked(m.now())
+}
+
+func (m *DetectorManager) evictIdleLocked(now time.Time) int {
+	if m.TTL <= 0 {
+		return 0
+	}
+
+	evicted := 0
+	for key, stream := range m.streams {
+		if now.Sub(stream.lastSeen) > m.TTL {
+			delete(m.streams, key)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+func (m *DetectorManager) evictOldestLocked() {
+	var oldestKey StreamKey
+	var oldest time.Time
+	found := false
+
+	for key, stream := range m.streams {
+		if !found || stream.lastSeen.Before(oldest) {
+			oldestKey = key
+			oldest = stream.lastSeen
+			found = true
+		}
+	}
+
+	if found {
+		delete(m.streams, oldestKey)
+	}
+}
+
+// Run menjalankan EvictIdle secara berkala sampai ctx selesai
+func (m *DetectorManager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.EvictIdle()
+		}
+	}
+}
+
+// Detector mengembalikan detector milik stream key jika ada.
+// Detector tidak dikunci, jangan dipakai bersamaan dengan AddData
+func (m *DetectorManager) Detector(key StreamKey) (SequentialDetector, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stream, exists := m.streams[key]
+	if !exists {
+		return nil, false
+	}
+	return stream.detector, true
+}
+
+// Remove menghapus stream secara manual
+func (m *DetectorManager) Remove(key StreamKey) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.streams, key)
+}
+
+// Len mengembalikan jumlah stream yang aktif
+func (m *DetectorManager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.streams)
+}
+
+// Keys mengembalikan semua stream key yang aktif secara terurut
+func (m *DetectorManager) Keys() []StreamKey {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]StreamKey, 0, len(m.streams))
+	for key := range m.streams {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].VehicleID != keys[j].VehicleID {
+			return keys[i].VehicleID < keys[j].VehicleID
+		}
+		return keys[i].ECUID < keys[j].ECUID
+	})
+	return keys
+}
@@ -0,0 +1,295 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// DetectorSnapshotVersion adalah versi format snapshot yang ditulis saat ini
+const DetectorSnapshotVersion = 1
+
+// Record adalah SequentialProvider generik, dipakai untuk history hasil restore
+type Record struct {
+	Timestamp float64
+	Names     []string
+	Values    []int
+}
+
+func (r Record) GetFeatureValue(feature int) int {
+	if feature < 0 || feature >= len(r.Values) {
+		return 0
+	}
+	return r.Values[feature]
+}
+
+func (r Record) GetFeatureCount() int { return len(r.Values) }
+
+func (r Record) GetFeatureName(feature int) string {
+	if feature < 0 || feature >= len(r.Names) {
+		return ""
+	}
+	return r.Names[feature]
+}
+
+func (r Record) GetTimestamp() float64 { return r.Timestamp }
+
+// NewRecord menyalin isi SequentialProvider menjadi Record
+func NewRecord(data SequentialProvider) Record {
+	record := Record{
+		Timestamp: data.GetTimestamp(),
+		Names:     make([]string, data.GetFeatureCount()),
+		Values:    make([]int, data.GetFeatureCount()),
+	}
+	for i := 0; i < data.GetFeatureCount(); i++ {
+		record.Names[i] = data.GetFeatureName(i)
+		record.Values[i] = data.GetFeatureValue(i)
+	}
+	return record
+}
+
+// ComparatorSnapshot menyimpan comparator sebagai nama terdaftar dan parameternya
+type ComparatorSnapshot struct {
+	Type   string
+	Params json.RawMessage `json:",omitempty"`
+}
+
+// WindowCheckSnapshot menyimpan satu WindowCheck
+type WindowCheckSnapshot struct {
+	Threshold  float64
+	Comparator ComparatorSnapshot
+}
+
+// FeatureSnapshot menyimpan satu FeatureConfig
+type FeatureSnapshot struct {
+	Name             string
+	Threshold        float64
+	Comparator       *ComparatorSnapshot   `json:",omitempty"`
+	RecordComparator *ComparatorSnapshot   `json:",omitempty"`
+	WindowChecks     []WindowCheckSnapshot `json:",omitempty"`
+}
+
+// DetectorSnapshot adalah state lengkap WindowDetector yang bisa disimpan ke file
+type DetectorSnapshot struct {
+	Version         int
+	WindowSize      int
+	CheckTimestamps bool
+	MaxGap          float64
+	Features        []FeatureSnapshot
+	History         []Record
+}
+
+// Snapshot menyimpan state detector. Semua comparator harus terdaftar di registry
+func (wd *WindowDetector) Snapshot() (*DetectorSnapshot, error) {
+	snapshot := &DetectorSnapshot{
+		Version:         DetectorSnapshotVersion,
+		WindowSize:      wd.WindowSize,
+		CheckTimestamps: wd.CheckTimestamps,
+		MaxGap:          wd.MaxGap,
+		History:         make([]Record, 0, len(wd.History)),
+	}
+
+	// Urutkan nama feature agar file snapshot selalu sama
+	featureNames := make([]string, 0, len(wd.FeatureConfigs))
+	for featureName := range wd.FeatureConfigs {
+		featureNames = append(featureNames, featureName)
+	}
+	sort.Strings(featureNames)
+
+	for _, featureName := range featureNames {
+		config := wd.FeatureConfigs[featureName]
+
+		feature := FeatureSnapshot{
+			Name:      config.Name,
+			Threshold: config.Threshold,
+		}
+
+		if config.Comparator != nil {
+			comparator, err := snapshotComparator(config.Comparator)
+			if err != nil {
+				return nil, fmt.Errorf("feature %s: %v", featureName, err)
+			}
+			feature.Comparator = &comparator
+		}
+
+		if config.RecordComparator != nil {
+			comparator, err := snapshotComparator(config.RecordComparator)
+			if err != nil {
+				return nil, fmt.Errorf("feature %s: %v", featureName, err)
+			}
+			feature.RecordComparator = &comparator
+		}
+
+		for _, check := range config.WindowChecks {
+			comparator, err := snapshotComparator(check.Comparator)
+			if err != nil {
+				return nil, fmt.Errorf("feature %s: %v", featureName, err)
+			}
+			feature.WindowChecks = append(feature.WindowChecks, WindowCheckSnapshot{
+				Threshold:  check.Threshold,
+				Comparator: comparator,
+			})
+		}
+
+		snapshot.Features = append(snapshot.Features, feature)
+	}
+
+	for _, data := range wd.History {
+		snapshot.History = append(snapshot.History, NewRecord(data))
+	}
+
+	return snapshot, nil
+}
+
+func snapshotComparator(comparator any) (ComparatorSnapshot, error) {
+	name, exists := ComparatorName(comparator)
+	if !exists {
+		return ComparatorSnapshot{}, fmt.Errorf("comparator %T is not registered", comparator)
+	}
+
+	params, err := ComparatorParams(comparator)
+	if err != nil {
+		return ComparatorSnapshot{}, err
+	}
+
+	return ComparatorSnapshot{Type: name, Params: params}, nil
+}
+
+// Validate mengecek snapshot dengan aturan yang sama seperti DetectorConfig.Validate
+func (s *DetectorSnapshot) Validate() error {
+	if s.Version == 0 {
+		return fmt.Errorf("snapshot has no version")
+	}
+	if s.Version > DetectorSnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d, max %d", s.Version, DetectorSnapshotVersion)
+	}
+	if s.WindowSize < 2 {
+		return fmt.Errorf("windowSize must be at least 2, got %d", s.WindowSize)
+	}
+	if s.MaxGap < 0 {
+		return fmt.Errorf("maxGap must not be negative")
+	}
+
+	names := make(map[string]bool)
+	for i, feature := range s.Features {
+		if feature.Name == "" {
+			return fmt.Errorf("feature %d has no name", i)
+		}
+		if names[feature.Name] {
+			return fmt.Errorf("feature %s is defined more than once", feature.Name)
+		}
+		names[feature.Name] = true
+
+		for _, comparator := range []*ComparatorSnapshot{feature.Comparator, feature.RecordComparator} {
+			if comparator == nil {
+				continue
+			}
+			if err := validateComparatorType(comparator.Type); err != nil {
+				return fmt.Errorf("feature %s: %v", feature.Name, err)
+			}
+		}
+		for _, check := range feature.WindowChecks {
+			if err := validateComparatorType(check.Comparator.Type); err != nil {
+				return fmt.Errorf("feature %s: window check: %v", feature.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// RestoreWindowDetector membuat WindowDetector dari snapshot.
+// History yang lebih panjang dari WindowSize dipotong, hanya data terbaru yang disimpan
+func RestoreWindowDetector(snapshot *DetectorSnapshot) (*WindowDetector, error) {
+	if err := snapshot.Validate(); err != nil {
+		return nil, err
+	}
+
+	wd := NewWindowDetector(snapshot.WindowSize)
+	wd.CheckTimestamps = snapshot.CheckTimestamps
+	wd.MaxGap = snapshot.MaxGap
+
+	for _, feature := range snapshot.Features {
+		config := FeatureConfig{
+			Name:      feature.Name,
+			Threshold: feature.Threshold,
+		}
+
+		if feature.Comparator != nil {
+			comparator, err := newFeatureComparator(feature.Comparator.Type, feature.Comparator.Params)
+			if err != nil {
+				return nil, fmt.Errorf("feature %s: %v", feature.Name, err)
+			}
+			config.Comparator = comparator
+		}
+
+		if feature.RecordComparator != nil {
+			comparator, err := newRecordComparator(feature.RecordComparator.Type, feature.RecordComparator.Params)
+			if err != nil {
+				return nil, fmt.Errorf("feature %s: %v", feature.Name, err)
+			}
+			config.RecordComparator = comparator
+		}
+
+		for _, check := range feature.WindowChecks {
+			comparator, err := newWindowComparator(check.Comparator.Type, check.Comparator.Params)
+			if err != nil {
+				return nil, fmt.Errorf("feature %s: %v", feature.Name, err)
+			}
+			config.WindowChecks = append(config.WindowChecks, WindowCheck{
+				Threshold:  check.Threshold,
+				Comparator: comparator,
+			})
+		}
+
+		if err := wd.AddFeatureConfig(config); err != nil {
+			return nil, err
+		}
+	}
+
+	history := snapshot.History
+	if len(history) > wd.WindowSize {
+		history = history[len(history)-wd.WindowSize:]
+	}
+	for _, record := range history {
+		wd.History = append(wd.History, record)
+	}
+
+	return wd, nil
+}
+
+// SaveState menyimpan state detector ke file
+func (wd *WindowDetector) SaveState(filename string) error {
+	snapshot, err := wd.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot)
+}
+
+// LoadWindowDetector membaca state detector dari file
+func LoadWindowDetector(filename string) (*WindowDetector, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var snapshot DetectorSnapshot
+	decoder := json.NewDecoder(file)
+	if err := decoder.Decode(&snapshot); err != nil {
+		return nil, err
+	}
+
+	return RestoreWindowDetector(&snapshot)
+}
@@ -0,0 +1,98 @@
+package ml
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RuleResult adalah hasil evaluasi satu rule bernama
+type RuleResult struct {
+	Name     string
+	Fired    bool
+	Severity Severity
+	Message  string
+	Error    string // diisi jika rule gagal dievaluasi, rule tersebut dianggap tidak terpicu
+}
+
+// RuleComparator adalah RecordComparator yang terdiri dari beberapa rule bernama.
+// WindowDetector melaporkan setiap rule sebagai FeatureResult tersendiri.
+// Kegagalan satu rule dicatat di RuleResult.Error. Error yang dikembalikan berarti seluruh comparator gagal,
+// dicatat di FeatureResult.Error seperti ErrorComparator
+type RuleComparator interface {
+	RecordComparator
+	EvaluateRules(prev, current SequentialProvider, window []SequentialProvider) ([]RuleResult, error)
+}
+
+// ExpressionComparator menjalankan sekumpulan ExpressionRule terhadap record saat ini dan window
+type ExpressionComparator struct {
+	Rules []*ExpressionRule
+}
+
+func init() {
+	RegisterComparator("expression", func() any { return &ExpressionComparator{} })
+}
+
+// NewExpressionComparator membuat comparator dari rule yang sudah di-compile
+func NewExpressionComparator(rules ...*ExpressionRule) *ExpressionComparator {
+	return &ExpressionComparator{Rules: rules}
+}
+
+// AddRule meng-compile ekspresi dan menambahkannya sebagai rule baru
+func (c *ExpressionComparator) AddRule(name, expression string, severity Severity) error {
+	for _, rule := range c.Rules {
+		if rule.Name == name {
+			return fmt.Errorf("rule %s already exists", name)
+		}
+	}
+
+	rule, err := CompileExpressionRule(name, expression, severity)
+	if err != nil {
+		return err
+	}
+	c.Rules = append(c.Rules, rule)
+	return nil
+}
+
+// EvaluateRules mengevaluasi semua rule secara berurutan.
+// Rule yang gagal (misalnya pembagian dengan nol atau feature tidak ada) dicatat di Error
+// dan dianggap tidak terpicu, rule lain tetap dievaluasi
+func (c *ExpressionComparator) EvaluateRules(prev, current SequentialProvider, window []SequentialProvider) ([]RuleResult, error) {
+	results := make([]RuleResult, 0, len(c.Rules))
+	for _, rule := range c.Rules {
+		result := RuleResult{Name: rule.Name, Severity: rule.Severity}
+
+		fired, err := rule.Evaluate(prev, current, window)
+		if err != nil {
+			result.Error = fmt.Sprintf("rule %s: %v", rule.Name, err)
+			results = append(results, result)
+			continue
+		}
+
+		result.Fired = fired
+		if fired {
+			result.Message = rule.Message()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// CompareRecords mengembalikan 1 jika ada rule yang terpicu, beserta pesan semua rule tersebut.
+// Rule yang gagal dianggap tidak terpicu, WindowDetector melaporkannya lewat EvaluateRules
+func (c *ExpressionComparator) CompareRecords(prev, current SequentialProvider, window []SequentialProvider) (float64, string) {
+	results, err := c.EvaluateRules(prev, current, window)
+	if err != nil {
+		return 0, ""
+	}
+
+	var messages []string
+	for _, result := range results {
+		if result.Fired {
+			messages = append(messages, result.Message)
+		}
+	}
+	if len(messages) == 0 {
+		return 0, ""
+	}
+	return 1.0, strings.Join(messages, "; ")
+}
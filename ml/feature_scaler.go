@@ -0,0 +1,42 @@
+package ml
+
+import "fmt"
+
+// FeatureScaler mengubah setiap feature menjadi z-score agar feature dengan skala besar
+// (misalnya rpm) tidak mendominasi jarak terhadap feature kecil (misalnya gear)
+type FeatureScaler struct {
+	Mean   []float64
+	StdDev []float64
+}
+
+// FitScaler menghitung rata-rata dan standar deviasi setiap feature dari dataset
+func FitScaler(dataset DataSet) (*FeatureScaler, error) {
+	if len(dataset) == 0 {
+		return nil, fmt.Errorf("dataset is empty")
+	}
+
+	count := dataset[0].GetFeatureCount()
+	scaler := &FeatureScaler{Mean: make([]float64, count), StdDev: make([]float64, count)}
+	values := make([]float64, len(dataset))
+	for feature := 0; feature < count; feature++ {
+		for i, data := range dataset {
+			values[i] = float64(data.GetFeatureValue(feature))
+		}
+		mean, stdDev := meanStdDev(values)
+		if stdDev == 0 {
+			// Feature konstan tidak diskalakan
+			stdDev = 1
+		}
+		scaler.Mean[feature], scaler.StdDev[feature] = mean, stdDev
+	}
+	return scaler, nil
+}
+
+// Transform mengembalikan vector feature yang sudah diskalakan
+func (s *FeatureScaler) Transform(data HasValueCount) []float64 {
+	vector := make([]float64, len(s.Mean))
+	for i := range vector {
+		vector[i] = (float64(data.GetFeatureValue(i)) - s.Mean[i]) / s.StdDev[i]
+	}
+	return vector
+}
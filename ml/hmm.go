@@ -0,0 +1,517 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+func init() {
+	RegisterComparator("hmm", func() any { return NewDrivingHMM() })
+}
+
+// HMM adalah hidden Markov model dengan emisi Gaussian diagonal.
+// Features berisi nama feature, atau "rate(nama)" untuk perubahan per detik
+type HMM struct {
+	States   []string
+	Features []string
+
+	Initial    []float64   // peluang state awal
+	Transition [][]float64 // Transition[i][j] = P(state j | state i)
+	Means      [][]float64 // Means[state][feature]
+	Variances  [][]float64 // Variances[state][feature]
+
+	MinVariance float64
+
+	// Threshold adalah negative log-likelihood per sampel dari window normal hasil kalibrasi
+	Threshold float64
+}
+
+// NewDrivingHMM membuat HMM dengan state mengemudi dan nilai awal yang masuk akal,
+// sehingga setelah Baum-Welch setiap state tetap sesuai dengan namanya
+func NewDrivingHMM() *HMM {
+	states := []string{"parked", "idle", "accelerating", "cruising", "braking", "shifting"}
+	means := [][]float64{
+		{0, 0, 0},       // parked: mesin mati
+		{850, 0, 0},     // idle
+		{2500, 40, 2},   // accelerating
+		{2000, 70, 0},   // cruising
+		{1500, 40, -3},  // braking
+		{1200, 40, 0.5}, // shifting: RPM turun sesaat
+	}
+	variances := [][]float64{
+		{100 * 100, 1, 0.25},
+		{100 * 100, 1, 0.25},
+		{600 * 600, 25 * 25, 1},
+		{500 * 500, 25 * 25, 0.5},
+		{600 * 600, 25 * 25, 2},
+		{400 * 400, 25 * 25, 1},
+	}
+
+	n := len(states)
+	hmm := &HMM{
+		States:      states,
+		Features:    []string{"rpm", "speed", "rate(speed)"},
+		Initial:     make([]float64, n),
+		Transition:  make([][]float64, n),
+		Means:       means,
+		Variances:   variances,
+		MinVariance: 1e-2,
+	}
+
+	// Cenderung tetap di state yang sama
+	for i := 0; i < n; i++ {
+		hmm.Initial[i] = 1 / float64(n)
+		hmm.Transition[i] = make([]float64, n)
+		for j := 0; j < n; j++ {
+			hmm.Transition[i][j] = 0.1 / float64(n-1)
+		}
+		hmm.Transition[i][i] = 0.9
+	}
+
+	return hmm
+}
+
+// Validate mengecek ukuran semua tabel
+func (h *HMM) Validate() error {
+	n, d := len(h.States), len(h.Features)
+	if n == 0 || d == 0 {
+		return fmt.Errorf("hmm needs at least one state and one feature")
+	}
+	if len(h.Initial) != n || len(h.Transition) != n || len(h.Means) != n || len(h.Variances) != n {
+		return fmt.Errorf("hmm tables must have %d states", n)
+	}
+	for i := 0; i < n; i++ {
+		if len(h.Transition[i]) != n {
+			return fmt.Errorf("transition row %d must have %d states", i, n)
+		}
+		if len(h.Means[i]) != d || len(h.Variances[i]) != d {
+			return fmt.Errorf("state %s must have %d features", h.States[i], d)
+		}
+	}
+	return nil
+}
+
+// CheckTrained memastikan model dari konfigurasi sudah dikalibrasi
+func (h *HMM) CheckTrained() error {
+	if err := h.Validate(); err != nil {
+		return err
+	}
+	if h.Threshold <= 0 {
+		return fmt.Errorf("hmm has no threshold, train and calibrate it first")
+	}
+	return nil
+}
+
+// Observations mengubah urutan data menjadi vektor observasi sesuai Features
+func (h *HMM) Observations(sequence []SequentialProvider) ([][]float64, error) {
+	observations := make([][]float64, len(sequence))
+	for t, data := range sequence {
+		observation := make([]float64, len(h.Features))
+		for f, feature := range h.Features {
+			name, isRate := strings.CutPrefix(feature, "rate(")
+			name = strings.TrimSuffix(name, ")")
+
+			value, exists := FeatureValueByName(data, name)
+			if !exists {
+				return nil, fmt.Errorf("feature not found in data: %s", name)
+			}
+
+			if isRate {
+				// Sampel pertama tidak punya pembanding
+				if t == 0 {
+					value = 0
+				} else {
+					prev, _ := FeatureValueByName(sequence[t-1], name)
+					value = (value - prev) / ElapsedSeconds(sequence[t-1], data)
+				}
+			}
+			observation[f] = value
+		}
+		observations[t] = observation
+	}
+	return observations, nil
+}
+
+// Log density emisi setiap state untuk satu observasi
+func (h *HMM) logEmissions(observation []float64) []float64 {
+	logs := make([]float64, len(h.States))
+	for i := range h.States {
+		for f, x := range observation {
+			variance := math.Max(h.Variances[i][f], h.MinVariance)
+			diff := x - h.Means[i][f]
+			logs[i] -= 0.5 * (math.Log(2*math.Pi*variance) + diff*diff/variance)
+		}
+	}
+	return logs
+}
+
+// Forward-backward dengan scaling. Mengembalikan gamma, jumlah xi dan log-likelihood
+func (h *HMM) forwardBackward(observations [][]float64) ([][]float64, [][]float64, float64) {
+	n, T := len(h.States), len(observations)
+
+	// Emisi dinormalisasi per waktu agar tidak underflow, offset masuk ke log-likelihood
+	emissions := make([][]float64, T)
+	var logLikelihood float64
+	for t, observation := range observations {
+		logs := h.logEmissions(observation)
+		best := math.Inf(-1)
+		for _, l := range logs {
+			best = math.Max(best, l)
+		}
+		emissions[t] = make([]float64, n)
+		for i, l := range logs {
+			emissions[t][i] = math.Exp(l - best)
+		}
+		logLikelihood += best
+	}
+
+	alpha := make([][]float64, T)
+	scale := make([]float64, T)
+	for t := 0; t < T; t++ {
+		alpha[t] = make([]float64, n)
+		for j := 0; j < n; j++ {
+			if t == 0 {
+				alpha[t][j] = h.Initial[j] * emissions[t][j]
+				continue
+			}
+			var sum float64
+			for i := 0; i < n; i++ {
+				sum += alpha[t-1][i] * h.Transition[i][j]
+			}
+			alpha[t][j] = sum * emissions[t][j]
+		}
+
+		for j := 0; j < n; j++ {
+			scale[t] += alpha[t][j]
+		}
+		if scale[t] == 0 {
+			scale[t] = math.SmallestNonzeroFloat64
+		}
+		for j := 0; j < n; j++ {
+			alpha[t][j] /= scale[t]
+		}
+		logLikelihood += math.Log(scale[t])
+	}
+
+	beta := make([][]float64, T)
+	beta[T-1] = make([]float64, n)
+	for i := range beta[T-1] {
+		beta[T-1][i] = 1
+	}
+	for t := T - 2; t >= 0; t-- {
+		beta[t] = make([]float64, n)
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				beta[t][i] += h.Transition[i][j] * emissions[t+1][j] * beta[t+1][j]
+			}
+			beta[t][i] /= scale[t+1]
+		}
+	}
+
+	gamma := make([][]float64, T)
+	for t := 0; t < T; t++ {
+		gamma[t] = make([]float64, n)
+		var sum float64
+		for i := 0; i < n; i++ {
+			gamma[t][i] = alpha[t][i] * beta[t][i]
+			sum += gamma[t][i]
+		}
+		for i := 0; i < n && sum > 0; i++ {
+			gamma[t][i] /= sum
+		}
+	}
+
+	xi := make([][]float64, n)
+	for i := range xi {
+		xi[i] = make([]float64, n)
+	}
+	for t := 0; t < T-1; t++ {
+		var sum float64
+		step := make([][]float64, n)
+		for i := 0; i < n; i++ {
+			step[i] = make([]float64, n)
+			for j := 0; j < n; j++ {
+				step[i][j] = alpha[t][i] * h.Transition[i][j] * emissions[t+1][j] * beta[t+1][j]
+				sum += step[i][j]
+			}
+		}
+		for i := 0; i < n && sum > 0; i++ {
+			for j := 0; j < n; j++ {
+				xi[i][j] += step[i][j] / sum
+			}
+		}
+	}
+
+	return gamma, xi, logLikelihood
+}
+
+// LogLikelihood menghitung log P(observations | model)
+func (h *HMM) LogLikelihood(observations [][]float64) float64 {
+	if len(observations) == 0 {
+		return 0
+	}
+	_, _, logLikelihood := h.forwardBackward(observations)
+	return logLikelihood
+}
+
+// Train melatih model dengan Baum-Welch pada urutan observasi tanpa label.
+// Berhenti setelah maxIterations atau jika kenaikan log-likelihood di bawah tolerance.
+// Mengembalikan log-likelihood total terakhir
+func (h *HMM) Train(sequences [][][]float64, maxIterations int, tolerance float64) (float64, error) {
+	if err := h.Validate(); err != nil {
+		return 0, err
+	}
+
+	n, d := len(h.States), len(h.Features)
+	previous := math.Inf(-1)
+	var total float64
+
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		initial := make([]float64, n)
+		transition := make([][]float64, n)
+		gammaSum := make([]float64, n)
+		gammaFrom := make([]float64, n)
+		weighted := make([][]float64, n)
+		for i := 0; i < n; i++ {
+			transition[i] = make([]float64, n)
+			weighted[i] = make([]float64, d)
+		}
+
+		// E-step
+		total = 0
+		var used int
+		gammas := make([][][]float64, len(sequences))
+		for s, observations := range sequences {
+			if len(observations) == 0 {
+				continue
+			}
+			used++
+
+			gamma, xi, logLikelihood := h.forwardBackward(observations)
+			gammas[s] = gamma
+			total += logLikelihood
+
+			for i := 0; i < n; i++ {
+				initial[i] += gamma[0][i]
+				for j := 0; j < n; j++ {
+					transition[i][j] += xi[i][j]
+				}
+				for t, observation := range observations {
+					gammaSum[i] += gamma[t][i]
+					if t < len(observations)-1 {
+						gammaFrom[i] += gamma[t][i]
+					}
+					for f, x := range observation {
+						weighted[i][f] += gamma[t][i] * x
+					}
+				}
+			}
+		}
+
+		if used == 0 {
+			return 0, fmt.Errorf("no training sequences")
+		}
+
+		// Variansi butuh mean yang baru
+		means := make([][]float64, n)
+		for i := 0; i < n; i++ {
+			means[i] = make([]float64, d)
+			for f := 0; f < d; f++ {
+				if gammaSum[i] > 0 {
+					means[i][f] = weighted[i][f] / gammaSum[i]
+				} else {
+					means[i][f] = h.Means[i][f]
+				}
+			}
+		}
+
+		variances := make([][]float64, n)
+		for i := 0; i < n; i++ {
+			variances[i] = make([]float64, d)
+		}
+		for s, observations := range sequences {
+			gamma := gammas[s]
+			for t, observation := range observations {
+				for i := 0; i < n; i++ {
+					for f, x := range observation {
+						diff := x - means[i][f]
+						variances[i][f] += gamma[t][i] * diff * diff
+					}
+				}
+			}
+		}
+
+		// M-step. State yang tidak terpakai dibiarkan apa adanya
+		for i := 0; i < n; i++ {
+			h.Initial[i] = (initial[i] + 1e-6) / (float64(used) + 1e-6*float64(n))
+
+			if gammaFrom[i] > 0 {
+				for j := 0; j < n; j++ {
+					h.Transition[i][j] = (transition[i][j] + 1e-6) / (gammaFrom[i] + 1e-6*float64(n))
+				}
+			}
+
+			if gammaSum[i] > 0 {
+				h.Means[i] = means[i]
+				for f := 0; f < d; f++ {
+					h.Variances[i][f] = math.Max(variances[i][f]/gammaSum[i], h.MinVariance)
+				}
+			}
+		}
+
+		if total-previous < tolerance {
+			break
+		}
+		previous = total
+	}
+
+	return total, nil
+}
+
+// Viterbi mengembalikan urutan state yang paling mungkin (index ke States) dan log-probabilitasnya
+func (h *HMM) Viterbi(observations [][]float64) ([]int, float64) {
+	n, T := len(h.States), len(observations)
+	if T == 0 {
+		return nil, 0
+	}
+
+	delta := make([][]float64, T)
+	backPointer := make([][]int, T)
+	for t, observation := range observations {
+		emissions := h.logEmissions(observation)
+		delta[t] = make([]float64, n)
+		backPointer[t] = make([]int, n)
+
+		for j := 0; j < n; j++ {
+			if t == 0 {
+				delta[t][j] = math.Log(h.Initial[j]) + emissions[j]
+				continue
+			}
+
+			best, bestState := math.Inf(-1), 0
+			for i := 0; i < n; i++ {
+				score := delta[t-1][i] + math.Log(h.Transition[i][j])
+				if score > best {
+					best, bestState = score, i
+				}
+			}
+			delta[t][j] = best + emissions[j]
+			backPointer[t][j] = bestState
+		}
+	}
+
+	path := make([]int, T)
+	path[T-1] = argMax(delta[T-1])
+	for t := T - 1; t > 0; t-- {
+		path[t-1] = backPointer[t][path[t]]
+	}
+	return path, delta[T-1][path[T-1]]
+}
+
+// StateNames mengubah path Viterbi menjadi nama state
+func (h *HMM) StateNames(path []int) []string {
+	names := make([]string, len(path))
+	for t, state := range path {
+		names[t] = h.States[state]
+	}
+	return names
+}
+
+// Score adalah negative log-likelihood per sampel, makin besar makin tidak wajar
+func (h *HMM) Score(observations [][]float64) float64 {
+	if len(observations) == 0 {
+		return 0
+	}
+	return -h.LogLikelihood(observations) / float64(len(observations))
+}
+
+// Calibrate menentukan Threshold sehingga sekitar falsePositiveRate dari window normal
+// berukuran windowSize melewatinya. Window dinilai sama persis seperti di CompareRecords
+func (h *HMM) Calibrate(sequences [][]SequentialProvider, windowSize int, falsePositiveRate float64) error {
+	if windowSize < 1 {
+		return fmt.Errorf("window size must be at least 1, got %d", windowSize)
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return fmt.Errorf("false positive rate must be between 0 and 1, got %f", falsePositiveRate)
+	}
+
+	var scores []float64
+	for _, sequence := range sequences {
+		for end := windowSize; end <= len(sequence); end++ {
+			observations, err := h.Observations(sequence[end-windowSize : end])
+			if err != nil {
+				return err
+			}
+			scores = append(scores, h.Score(observations))
+		}
+	}
+	if len(scores) == 0 {
+		return fmt.Errorf("no calibration windows of size %d", windowSize)
+	}
+
+	sort.Float64s(scores)
+	index := int(math.Ceil(float64(len(scores))*(1-falsePositiveRate))) - 1
+	h.Threshold = scores[min(max(index, 0), len(scores)-1)]
+	return nil
+}
+
+// CompareRecords menilai seluruh window. Pakai Threshold sebagai threshold FeatureConfig
+func (h *HMM) CompareRecords(prev, current SequentialProvider, window []SequentialProvider) (float64, string) {
+	score, reason, err := h.CompareRecordsWithError(prev, current, window)
+	if err != nil {
+		return 0, ""
+	}
+	return score, reason
+}
+
+// CompareRecordsWithError sama dengan CompareRecords, tetapi mengembalikan error
+// jika feature model tidak ada di data
+func (h *HMM) CompareRecordsWithError(prev, current SequentialProvider, window []SequentialProvider) (float64, string, error) {
+	observations, err := h.Observations(window)
+	if err != nil {
+		return 0, "", fmt.Errorf("hmm: %w", err)
+	}
+	if len(observations) == 0 {
+		return 0, "", nil
+	}
+
+	score := h.Score(observations)
+	path, _ := h.Viterbi(observations)
+	return score, fmt.Sprintf("unlikely driving sequence (log-likelihood per sample %.2f below %.2f): %s",
+		-score, -h.Threshold, strings.Join(h.StateNames(path), " -> ")), nil
+}
+
+// SaveHMM menyimpan model ke file JSON
+func (h *HMM) SaveHMM(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(h)
+}
+
+// LoadHMM membaca model dari file JSON
+func LoadHMM(filename string) (*HMM, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var hmm HMM
+	if err := json.NewDecoder(file).Decode(&hmm); err != nil {
+		return nil, err
+	}
+	if err := hmm.Validate(); err != nil {
+		return nil, err
+	}
+	return &hmm, nil
+}
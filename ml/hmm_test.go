@@ -0,0 +1,70 @@
+package ml
+
+import (
+	"math"
+	"testing"
+)
+
+// twoStateHMM punya state "low" di sekitar 0 dan "high" di sekitar 10 yang cenderung bertahan
+func twoStateHMM() *HMM {
+	return &HMM{
+		States:      []string{"low", "high"},
+		Features:    []string{"speed"},
+		Initial:     []float64{0.5, 0.5},
+		Transition:  [][]float64{{0.9, 0.1}, {0.1, 0.9}},
+		Means:       [][]float64{{0}, {10}},
+		Variances:   [][]float64{{1}, {1}},
+		MinVariance: 1e-2,
+	}
+}
+
+// bruteForceViterbi mencoba semua path dan mengembalikan log-probabilitas terbesar
+func bruteForceViterbi(h *HMM, observations [][]float64) float64 {
+	n, T := len(h.States), len(observations)
+	best := math.Inf(-1)
+	path := make([]int, T)
+	for code := 0; code < int(math.Pow(float64(n), float64(T))); code++ {
+		for t, rest := 0, code; t < T; t, rest = t+1, rest/n {
+			path[t] = rest % n
+		}
+
+		logProb := math.Log(h.Initial[path[0]]) + h.logEmissions(observations[0])[path[0]]
+		for t := 1; t < T; t++ {
+			logProb += math.Log(h.Transition[path[t-1]][path[t]]) + h.logEmissions(observations[t])[path[t]]
+		}
+		best = max(best, logProb)
+	}
+	return best
+}
+
+func TestViterbiKnownAnswer(t *testing.T) {
+	tests := []struct {
+		observations []float64
+		want         []string
+	}{
+		{[]float64{0, 10, 10, 0, 0}, []string{"low", "high", "high", "low", "low"}},
+		// 5.2 sedikit lebih dekat ke high, tetapi dua kali pindah state lebih mahal dari emisinya
+		{[]float64{0, 5.2, 0}, []string{"low", "low", "low"}},
+		{[]float64{10, 10, 5.2, 10}, []string{"high", "high", "high", "high"}},
+	}
+
+	h := twoStateHMM()
+	for _, test := range tests {
+		observations := make([][]float64, len(test.observations))
+		for i, x := range test.observations {
+			observations[i] = []float64{x}
+		}
+
+		path, logProb := h.Viterbi(observations)
+		names := h.StateNames(path)
+		for i := range test.want {
+			if names[i] != test.want[i] {
+				t.Errorf("%v: path %v, want %v", test.observations, names, test.want)
+				break
+			}
+		}
+		if want := bruteForceViterbi(h, observations); math.Abs(logProb-want) > 1e-9 {
+			t.Errorf("%v: log probability %v, want %v", test.observations, logProb, want)
+		}
+	}
+}
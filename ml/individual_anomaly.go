@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"math"
 	"os"
-	"strings"
+	"sort"
 	"time"
 
 	"math/rand"
@@ -74,14 +74,20 @@ func LoadDataFromCSV(filename string, createData DataFactory) (DataSet, error) {
 
 // Fungsi untuk membangun tree
 func (dataset DataSet) BuildTree(depth int, maxDepth int) *Node {
-	// Log indentasi untuk visualisasi kedalaman
-	indent := strings.Repeat("  ", depth)
+	return dataset.BuildTreeWithObserver(depth, maxDepth, nil)
+}
 
-	fmt.Printf("%sBuildTree: depth=%d, dataset size=%d\n", indent, depth, len(dataset))
+// BuildTreeWithObserver membangun tree dan mengirim setiap langkah ke observer.
+// observer nil berarti tidak ada log sama sekali
+func (dataset DataSet) BuildTreeWithObserver(depth int, maxDepth int, observer TrainingObserver) *Node {
+	if observer == nil {
+		observer = NopObserver{}
+	}
 
 	// Base case: jika dataset kosong
 	if len(dataset) == 0 {
-		fmt.Printf("%s└─ Empty dataset, returning leaf node (prediction=false)\n", indent)
+		observer.OnTrainingEvent(TrainingEvent{Type: EventNode, Depth: depth})
+		observer.OnTrainingEvent(TrainingEvent{Type: EventLeaf, Depth: depth, Prediction: false, Reason: LeafEmpty})
 		return &Node{
 			IsLeaf:     true,
 			Prediction: false,
@@ -90,47 +96,50 @@ func (dataset DataSet) BuildTree(depth int, maxDepth int) *Node {
 
 	// Base case: jika sudah mencapai max depth atau semua data punya label sama
 	attackProp := dataset.calculateAttackProportion()
-	fmt.Printf("%s├─ Attack proportion: %.2f%%\n", indent, attackProp*100)
+	event := TrainingEvent{
+		Type:             EventNode,
+		Depth:            depth,
+		Size:             len(dataset),
+		AttackProportion: attackProp,
+	}
+	observer.OnTrainingEvent(event)
 
-	if depth >= maxDepth {
-		fmt.Printf("%s└─ Reached max depth, returning leaf node (prediction=%v)\n",
-			indent, attackProp >= 0.5)
+	leaf := func(reason string) *Node {
+		event.Type = EventLeaf
+		event.Prediction = attackProp >= 0.5
+		event.Reason = reason
+		observer.OnTrainingEvent(event)
 		return &Node{
 			IsLeaf:     true,
 			Prediction: attackProp >= 0.5,
 		}
 	}
 
+	if depth >= maxDepth {
+		return leaf(LeafMaxDepth)
+	}
+
 	if attackProp == 0 || attackProp == 1 {
-		fmt.Printf("%s└─ Pure node (all %s), returning leaf node\n",
-			indent, map[bool]string{true: "attacks", false: "normal"}[attackProp == 1])
-		return &Node{
-			IsLeaf:     true,
-			Prediction: attackProp >= 0.5,
-		}
+		return leaf(LeafPure)
 	}
 
 	// Cari split terbaik
 	bestFeature, bestThreshold, bestGain := dataset.findBestSplit()
-	fmt.Printf("%s├─ Best split: feature=%s, threshold=%d, gain=%.4f\n",
-		indent,
-		map[int]string{0: "RPM", 1: "Gear", 2: "Speed"}[bestFeature],
-		bestThreshold,
-		bestGain)
 
 	if bestGain == 0 {
-		fmt.Printf("%s└─ No gain from splitting, returning leaf node (prediction=%v)\n",
-			indent, attackProp >= 0.5)
-		return &Node{
-			IsLeaf:     true,
-			Prediction: attackProp >= 0.5,
-		}
+		return leaf(LeafNoGain)
 	}
 
 	// Split dataset
 	leftData, rightData := dataset.splitDataset(bestFeature, bestThreshold)
-	fmt.Printf("%s├─ Split result: left=%d samples, right=%d samples\n",
-		indent, len(leftData), len(rightData))
+
+	event.Type = EventSplit
+	event.Feature = bestFeature
+	event.Threshold = bestThreshold
+	event.Gain = bestGain
+	event.LeftSize = len(leftData)
+	event.RightSize = len(rightData)
+	observer.OnTrainingEvent(event)
 
 	// Buat node
 	node := &Node{
@@ -140,11 +149,8 @@ func (dataset DataSet) BuildTree(depth int, maxDepth int) *Node {
 	}
 
 	// Rekursif untuk left dan right child
-	fmt.Printf("%s├─ Building left subtree...\n", indent)
-	node.Left = leftData.BuildTree(depth+1, maxDepth)
-
-	fmt.Printf("%s└─ Building right subtree...\n", indent)
-	node.Right = rightData.BuildTree(depth+1, maxDepth)
+	node.Left = leftData.BuildTreeWithObserver(depth+1, maxDepth, observer)
+	node.Right = rightData.BuildTreeWithObserver(depth+1, maxDepth, observer)
 
 	return node
 }
@@ -188,10 +194,15 @@ func (node *Node) GetPredictionAccuration(testData DataSet) float64 {
 }
 
 func (node *Node) PrintTree() {
-	node.printTree("", true)
+	node.PrintTreeWithNames([]string{"RPM", "Gear", "Speed"})
+}
+
+// PrintTreeWithNames mencetak tree dengan nama feature sendiri, misalnya hasil WindowTransformer
+func (node *Node) PrintTreeWithNames(featureNames []string) {
+	node.printTree("", true, featureNames)
 }
 
-func (node *Node) printTree(prefix string, isLeft bool) {
+func (node *Node) printTree(prefix string, isLeft bool, featureNames []string) {
 	if node == nil {
 		return
 	}
@@ -210,9 +221,11 @@ func (node *Node) printTree(prefix string, isLeft bool) {
 		return
 	}
 
-	fmt.Printf("%s%s [%s <= %d]\n", prefix, connector,
-		map[int]string{0: "RPM", 1: "Gear", 2: "Speed"}[node.Feature],
-		node.Threshold)
+	featureName := fmt.Sprintf("feature%d", node.Feature)
+	if node.Feature < len(featureNames) {
+		featureName = featureNames[node.Feature]
+	}
+	fmt.Printf("%s%s [%s <= %d]\n", prefix, connector, featureName, node.Threshold)
 
 	// Tentukan prefix untuk child nodes
 	newPrefix := prefix
@@ -222,8 +235,8 @@ func (node *Node) printTree(prefix string, isLeft bool) {
 		newPrefix += "    "
 	}
 
-	node.Left.printTree(newPrefix, true)
-	node.Right.printTree(newPrefix, false)
+	node.Left.printTree(newPrefix, true, featureNames)
+	node.Right.printTree(newPrefix, false, featureNames)
 }
 
 // Fungsi untuk save model ke file
@@ -255,26 +268,58 @@ func LoadModel(filename string) (*Node, error) {
 	return &root, nil
 }
 
-// Fungsi untuk mencari split terbaik
+// Fungsi untuk mencari split terbaik.
+// Nilai setiap feature diurutkan sekali lalu information gain semua threshold
+// dihitung dalam satu sapuan dari jumlah attack di kiri dan kanan split
 func (dataset DataSet) findBestSplit() (bestFeature int, bestThreshold int, bestGain float64) {
 	bestGain = 0
 
-	// Untuk setiap feature (RPM, Gear, Speed)
-	for feature := 0; feature < 3; feature++ {
-		// Cari nilai unik untuk feature ini
-		values := make(map[int]bool)
-		for _, data := range dataset {
-			// values[  getFeatureValue(data, feature)] = true
-			values[data.GetFeatureValue(feature)] = true
+	if len(dataset) == 0 {
+		return
+	}
+
+	total := len(dataset)
+	totalAttacks := 0
+	for _, data := range dataset {
+		if data.IsAnomaly() {
+			totalAttacks++
+		}
+	}
+	parentEntropy := binaryEntropy(totalAttacks, total)
+
+	type point struct {
+		value  int
+		attack bool
+	}
+	points := make([]point, total)
+
+	// Untuk setiap feature, jumlahnya mengikuti data (RPM, Gear, Speed untuk ECUData)
+	for feature := 0; feature < dataset[0].GetFeatureCount(); feature++ {
+		for i, data := range dataset {
+			points[i] = point{value: data.GetFeatureValue(feature), attack: data.IsAnomaly()}
 		}
+		sort.Slice(points, func(i, j int) bool { return points[i].value < points[j].value })
+
+		// Setiap nilai unik adalah kandidat threshold (kiri: value <= threshold)
+		leftCount, leftAttacks := 0, 0
+		for i, p := range points {
+			leftCount++
+			if p.attack {
+				leftAttacks++
+			}
+			if i+1 < total && points[i+1].value == p.value {
+				continue
+			}
 
-		// Untuk setiap nilai possible threshold
-		for threshold := range values {
-			gain := dataset.calculateInformationGain(feature, threshold)
+			rightCount := total - leftCount
+			weightedEntropy := float64(leftCount)/float64(total)*binaryEntropy(leftAttacks, leftCount) +
+				float64(rightCount)/float64(total)*binaryEntropy(totalAttacks-leftAttacks, rightCount)
+
+			gain := parentEntropy - weightedEntropy
 			if gain > bestGain {
 				bestGain = gain
 				bestFeature = feature
-				bestThreshold = threshold
+				bestThreshold = p.value
 			}
 		}
 	}
@@ -282,35 +327,12 @@ func (dataset DataSet) findBestSplit() (bestFeature int, bestThreshold int, best
 	return
 }
 
-// Fungsi untuk menghitung information gain
-func (dataset DataSet) calculateInformationGain(feature int, threshold int) float64 {
-	parentEntropy := dataset.calculateEntropy()
-
-	leftData, rightData := dataset.splitDataset(feature, threshold)
-
-	// Hitung weighted entropy setelah split
-	leftWeight := float64(len(leftData)) / float64(len(dataset))
-	rightWeight := float64(len(rightData)) / float64(len(dataset))
-
-	leftEntropy := leftData.calculateEntropy()
-	rightEntropy := rightData.calculateEntropy()
-
-	weightedEntropy := leftWeight*leftEntropy + rightWeight*rightEntropy
-
-	return parentEntropy - weightedEntropy
-}
-
-// Fungsi untuk menghitung entropy
-func (dataset DataSet) calculateEntropy() float64 {
-	if len(dataset) == 0 {
-		return 0
-	}
-
-	attackProp := dataset.calculateAttackProportion()
-	if attackProp == 0 || attackProp == 1 {
+// Entropy dari jumlah attack di antara total data
+func binaryEntropy(attacks, total int) float64 {
+	if total == 0 || attacks == 0 || attacks == total {
 		return 0
 	}
-
+	attackProp := float64(attacks) / float64(total)
 	return -attackProp*log2(attackProp) - (1-attackProp)*log2(1-attackProp)
 }
 
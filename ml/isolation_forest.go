@@ -0,0 +1,186 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+)
+
+// IsolationNode adalah node isolation tree. Data dengan value < SplitValue ke kiri
+type IsolationNode struct {
+	Feature    int            `json:",omitempty"`
+	SplitValue float64        `json:",omitempty"`
+	Left       *IsolationNode `json:",omitempty"`
+	Right      *IsolationNode `json:",omitempty"`
+	Size       int            `json:",omitempty"` // jumlah data di leaf
+}
+
+func (n *IsolationNode) isLeaf() bool {
+	return n.Left == nil && n.Right == nil
+}
+
+// IsolationForest mendeteksi anomali tanpa label: data yang mudah dipisahkan
+// dengan split acak (path pendek) dianggap anomali. IsAnomaly data latih diabaikan
+type IsolationForest struct {
+	Trees        []*IsolationNode
+	SampleSize   int
+	FeatureCount int
+	Seed         int64
+
+	// Threshold skor untuk Predict, skor mendekati 1 berarti anomali
+	Threshold float64
+}
+
+// TrainIsolationForest melatih forest dengan sub-sample berukuran sampleSize per tree.
+// Seed yang sama menghasilkan forest yang sama
+func TrainIsolationForest(dataset DataSet, treeCount int, sampleSize int, seed int64) (*IsolationForest, error) {
+	if len(dataset) < 2 {
+		return nil, fmt.Errorf("isolation forest needs at least 2 samples, got %d", len(dataset))
+	}
+	if treeCount < 1 {
+		return nil, fmt.Errorf("tree count must be at least 1, got %d", treeCount)
+	}
+
+	sampleSize = min(max(sampleSize, 2), len(dataset))
+	forest := &IsolationForest{
+		SampleSize:   sampleSize,
+		FeatureCount: dataset[0].GetFeatureCount(),
+		Seed:         seed,
+		Threshold:    0.6,
+	}
+
+	random := rand.New(rand.NewSource(seed))
+	heightLimit := int(math.Ceil(math.Log2(float64(sampleSize))))
+
+	for t := 0; t < treeCount; t++ {
+		sample := make([]HasValueCount, sampleSize)
+		for i, index := range random.Perm(len(dataset))[:sampleSize] {
+			sample[i] = dataset[index]
+		}
+		forest.Trees = append(forest.Trees, buildIsolationTree(sample, 0, heightLimit, forest.FeatureCount, random))
+	}
+
+	return forest, nil
+}
+
+func buildIsolationTree(data []HasValueCount, height, heightLimit, featureCount int, random *rand.Rand) *IsolationNode {
+	if height >= heightLimit || len(data) <= 1 {
+		return &IsolationNode{Size: len(data)}
+	}
+
+	// Pilih feature acak yang masih punya variasi
+	for _, feature := range random.Perm(featureCount) {
+		low, high := math.Inf(1), math.Inf(-1)
+		for _, d := range data {
+			value := float64(d.GetFeatureValue(feature))
+			low, high = math.Min(low, value), math.Max(high, value)
+		}
+		if low == high {
+			continue
+		}
+
+		split := low + random.Float64()*(high-low)
+		var left, right []HasValueCount
+		for _, d := range data {
+			if float64(d.GetFeatureValue(feature)) < split {
+				left = append(left, d)
+			} else {
+				right = append(right, d)
+			}
+		}
+
+		return &IsolationNode{
+			Feature:    feature,
+			SplitValue: split,
+			Left:       buildIsolationTree(left, height+1, heightLimit, featureCount, random),
+			Right:      buildIsolationTree(right, height+1, heightLimit, featureCount, random),
+		}
+	}
+
+	// Semua data sama, tidak bisa dipisahkan lagi
+	return &IsolationNode{Size: len(data)}
+}
+
+// Rata-rata panjang path pencarian yang gagal di binary search tree dengan n data
+func averagePathLength(n int) float64 {
+	switch {
+	case n <= 1:
+		return 0
+	case n == 2:
+		return 1
+	}
+	harmonic := math.Log(float64(n-1)) + 0.5772156649
+	return 2*harmonic - 2*float64(n-1)/float64(n)
+}
+
+func (n *IsolationNode) pathLength(data HasValueCount, height int) float64 {
+	if n.isLeaf() {
+		return float64(height) + averagePathLength(n.Size)
+	}
+	if float64(data.GetFeatureValue(n.Feature)) < n.SplitValue {
+		return n.Left.pathLength(data, height+1)
+	}
+	return n.Right.pathLength(data, height+1)
+}
+
+// Score mengembalikan skor anomali antara 0 dan 1.
+// Mendekati 1 berarti anomali, jauh di bawah 0.5 berarti normal
+func (f *IsolationForest) Score(data HasValueCount) float64 {
+	if len(f.Trees) == 0 {
+		return 0
+	}
+
+	var total float64
+	for _, tree := range f.Trees {
+		total += tree.pathLength(data, 0)
+	}
+	average := total / float64(len(f.Trees))
+	return math.Pow(2, -average/averagePathLength(f.SampleSize))
+}
+
+// Predict mengembalikan true jika skor melewati Threshold
+func (f *IsolationForest) Predict(data FeatureProvider) bool {
+	return f.Score(data) > f.Threshold
+}
+
+// SetContamination memilih Threshold sehingga sekitar rate bagian dari dataset dianggap anomali
+func (f *IsolationForest) SetContamination(dataset DataSet, rate float64) error {
+	threshold, err := ScoreThreshold(f, dataset, rate)
+	if err != nil {
+		return err
+	}
+	f.Threshold = threshold
+	return nil
+}
+
+// SaveModel menyimpan forest ke file JSON
+func (f *IsolationForest) SaveModel(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	return encoder.Encode(f)
+}
+
+// LoadIsolationForest membaca forest dari file JSON
+func LoadIsolationForest(filename string) (*IsolationForest, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var forest IsolationForest
+	if err := json.NewDecoder(file).Decode(&forest); err != nil {
+		return nil, err
+	}
+	if len(forest.Trees) == 0 {
+		return nil, fmt.Errorf("isolation forest %s has no trees", filename)
+	}
+	return &forest, nil
+}
@@ -0,0 +1,132 @@
+package ml
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// Neighbor adalah satu tetangga hasil pencarian KD-tree
+type Neighbor struct {
+	Index    int
+	Distance float64
+}
+
+// KDTree mempercepat pencarian k tetangga terdekat (jarak Euclidean)
+type KDTree struct {
+	points [][]float64
+	root   *kdNode
+}
+
+type kdNode struct {
+	index       int
+	axis        int
+	left, right *kdNode
+}
+
+// NewKDTree membangun KD-tree, points tidak disalin jadi jangan diubah setelahnya
+func NewKDTree(points [][]float64) *KDTree {
+	indexes := make([]int, len(points))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	tree := &KDTree{points: points}
+	tree.root = tree.build(indexes)
+	return tree
+}
+
+func (t *KDTree) build(indexes []int) *kdNode {
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	// Split di axis dengan sebaran terbesar agar tree tetap seimbang untuk feature dengan skala berbeda
+	axis, spread := 0, -1.0
+	for d := range t.points[indexes[0]] {
+		low, high := math.Inf(1), math.Inf(-1)
+		for _, i := range indexes {
+			low, high = math.Min(low, t.points[i][d]), math.Max(high, t.points[i][d])
+		}
+		if high-low > spread {
+			axis, spread = d, high-low
+		}
+	}
+
+	sort.Slice(indexes, func(a, b int) bool { return t.points[indexes[a]][axis] < t.points[indexes[b]][axis] })
+	median := len(indexes) / 2
+	return &kdNode{
+		index: indexes[median],
+		axis:  axis,
+		left:  t.build(indexes[:median]),
+		right: t.build(indexes[median+1:]),
+	}
+}
+
+// neighborHeap adalah max-heap berdasarkan jarak, tetangga terjauh ada di atas
+type neighborHeap []Neighbor
+
+func (h neighborHeap) Len() int           { return len(h) }
+func (h neighborHeap) Less(i, j int) bool { return h[i].Distance > h[j].Distance }
+func (h neighborHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *neighborHeap) Push(x any)        { *h = append(*h, x.(Neighbor)) }
+func (h *neighborHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// KNearest mengembalikan k tetangga terdekat dari query, urut dari yang terdekat.
+// Point dengan index skip tidak ikut dicari (-1 jika tidak ada), dipakai saat query adalah data latih
+func (t *KDTree) KNearest(query []float64, k int, skip int) []Neighbor {
+	if k <= 0 {
+		return nil
+	}
+
+	found := &neighborHeap{}
+	t.search(t.root, query, k, skip, found)
+
+	result := make([]Neighbor, found.Len())
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(found).(Neighbor)
+	}
+	return result
+}
+
+func (t *KDTree) search(node *kdNode, query []float64, k int, skip int, found *neighborHeap) {
+	if node == nil {
+		return
+	}
+
+	point := t.points[node.index]
+	if node.index != skip {
+		distance := euclidean(query, point)
+		if found.Len() < k {
+			heap.Push(found, Neighbor{Index: node.index, Distance: distance})
+		} else if distance < (*found)[0].Distance {
+			(*found)[0] = Neighbor{Index: node.index, Distance: distance}
+			heap.Fix(found, 0)
+		}
+	}
+
+	diff := query[node.axis] - point[node.axis]
+	near, far := node.left, node.right
+	if diff > 0 {
+		near, far = far, near
+	}
+	t.search(near, query, k, skip, found)
+
+	// Sisi lain hanya dicek jika bidang split lebih dekat dari tetangga terjauh
+	if found.Len() < k || math.Abs(diff) < (*found)[0].Distance {
+		t.search(far, query, k, skip, found)
+	}
+}
+
+func euclidean(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
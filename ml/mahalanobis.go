@@ -0,0 +1,308 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// GaussianModel adalah distribusi normal multivariat dari data normal
+type GaussianModel struct {
+	Count      int
+	Mean       []float64
+	Covariance [][]float64
+	Inverse    [][]float64 // invers covariance setelah regularisasi
+}
+
+// FitGaussian menghitung mean dan covariance dari vectors.
+// Regularization (relatif terhadap rata-rata variance) ditambahkan ke diagonal
+// agar covariance tetap bisa diinvers walaupun ada feature yang konstan
+func FitGaussian(vectors [][]float64, regularization float64) (*GaussianModel, error) {
+	if len(vectors) < 2 {
+		return nil, fmt.Errorf("gaussian model needs at least 2 samples, got %d", len(vectors))
+	}
+
+	dims := len(vectors[0])
+	model := &GaussianModel{Count: len(vectors), Mean: make([]float64, dims), Covariance: make([][]float64, dims)}
+	for _, v := range vectors {
+		for i := range v {
+			model.Mean[i] += v[i]
+		}
+	}
+	for i := range model.Mean {
+		model.Mean[i] /= float64(len(vectors))
+	}
+
+	var trace float64
+	for i := range model.Covariance {
+		model.Covariance[i] = make([]float64, dims)
+		for j := range model.Covariance[i] {
+			var sum float64
+			for _, v := range vectors {
+				sum += (v[i] - model.Mean[i]) * (v[j] - model.Mean[j])
+			}
+			model.Covariance[i][j] = sum / float64(len(vectors)-1)
+		}
+		trace += model.Covariance[i][i]
+	}
+
+	ridge := regularization*trace/float64(dims) + 1e-9
+	regularized := make([][]float64, dims)
+	for i := range regularized {
+		regularized[i] = append([]float64(nil), model.Covariance[i]...)
+		regularized[i][i] += ridge
+	}
+
+	inverse, err := invertMatrix(regularized)
+	if err != nil {
+		return nil, err
+	}
+	model.Inverse = inverse
+	return model, nil
+}
+
+// Distance adalah jarak Mahalanobis dari vector ke mean
+func (g *GaussianModel) Distance(vector []float64) float64 {
+	var sum float64
+	for i := range vector {
+		for j := range vector {
+			sum += (vector[i] - g.Mean[i]) * g.Inverse[i][j] * (vector[j] - g.Mean[j])
+		}
+	}
+	return math.Sqrt(max(sum, 0))
+}
+
+// invertMatrix menghitung invers dengan eliminasi Gauss-Jordan dan partial pivoting
+func invertMatrix(matrix [][]float64) ([][]float64, error) {
+	n := len(matrix)
+	augmented := make([][]float64, n)
+	for i := range matrix {
+		augmented[i] = make([]float64, 2*n)
+		copy(augmented[i], matrix[i])
+		augmented[i][n+i] = 1
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(augmented[row][col]) > math.Abs(augmented[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(augmented[pivot][col]) < 1e-12 {
+			return nil, fmt.Errorf("matrix is singular")
+		}
+		augmented[col], augmented[pivot] = augmented[pivot], augmented[col]
+
+		scale := augmented[col][col]
+		for j := range augmented[col] {
+			augmented[col][j] /= scale
+		}
+		for row := 0; row < n; row++ {
+			if row == col || augmented[row][col] == 0 {
+				continue
+			}
+			factor := augmented[row][col]
+			for j := range augmented[row] {
+				augmented[row][j] -= factor * augmented[col][j]
+			}
+		}
+	}
+
+	inverse := make([][]float64, n)
+	for i := range augmented {
+		inverse[i] = augmented[i][n:]
+	}
+	return inverse, nil
+}
+
+// MahalanobisDetector adalah baseline statistik one-class: data diskor dengan jarak Mahalanobis
+// ke distribusi data normal. Jika GroupFeature >= 0, satu model dilatih untuk setiap nilai
+// feature tersebut (misalnya per gear) dan Global dipakai untuk nilai yang tidak pernah dilihat
+type MahalanobisDetector struct {
+	Features       []int // index feature yang dipakai, kosong berarti semua feature
+	FeatureNames   []string
+	GroupFeature   int
+	Groups         map[int]*GaussianModel `json:",omitempty"`
+	Global         *GaussianModel
+	Regularization float64
+	Threshold      float64
+
+	// MinGroupSize adalah jumlah data minimal agar grup punya model sendiri, grup yang lebih kecil
+	// memakai Global. 0 berarti 10 kali jumlah feature, covariance dari data lebih sedikit tidak stabil
+	MinGroupSize int
+}
+
+// NewMahalanobisDetector membuat detector tanpa grup dengan semua feature
+func NewMahalanobisDetector() *MahalanobisDetector {
+	return &MahalanobisDetector{GroupFeature: -1, Regularization: 1e-6}
+}
+
+func (m *MahalanobisDetector) vector(data HasValueCount) []float64 {
+	features := m.Features
+	if len(features) == 0 {
+		features = make([]int, data.GetFeatureCount())
+		for i := range features {
+			features[i] = i
+		}
+	}
+
+	vector := make([]float64, len(features))
+	for i, feature := range features {
+		vector[i] = float64(data.GetFeatureValue(feature))
+	}
+	return vector
+}
+
+// Train menghitung model Gaussian dari data normal. IsAnomaly diabaikan.
+// Grup dengan data lebih sedikit dari MinGroupSize tidak dibuatkan model sendiri
+func (m *MahalanobisDetector) Train(normal DataSet) error {
+	if len(normal) == 0 {
+		return fmt.Errorf("dataset is empty")
+	}
+
+	vectors := make([][]float64, len(normal))
+	groups := make(map[int][][]float64)
+	for i, data := range normal {
+		vectors[i] = m.vector(data)
+		if m.GroupFeature >= 0 {
+			group := data.GetFeatureValue(m.GroupFeature)
+			groups[group] = append(groups[group], vectors[i])
+		}
+	}
+
+	global, err := FitGaussian(vectors, m.Regularization)
+	if err != nil {
+		return err
+	}
+	m.Global = global
+
+	minGroupSize := m.MinGroupSize
+	if minGroupSize <= 0 {
+		minGroupSize = 10 * len(vectors[0])
+	}
+	// Covariance grup harus bisa diinvers walaupun MinGroupSize diisi terlalu kecil
+	minGroupSize = max(minGroupSize, len(vectors[0])+1)
+
+	m.Groups = nil
+	for group, members := range groups {
+		if len(members) < minGroupSize {
+			continue
+		}
+		model, err := FitGaussian(members, m.Regularization)
+		if err != nil {
+			return fmt.Errorf("group %d: %w", group, err)
+		}
+		if m.Groups == nil {
+			m.Groups = make(map[int]*GaussianModel)
+		}
+		m.Groups[group] = model
+	}
+	return nil
+}
+
+func (m *MahalanobisDetector) model(data HasValueCount) *GaussianModel {
+	if m.GroupFeature >= 0 {
+		if model, exists := m.Groups[data.GetFeatureValue(m.GroupFeature)]; exists {
+			return model
+		}
+	}
+	return m.Global
+}
+
+// Score adalah jarak Mahalanobis data ke model grupnya
+func (m *MahalanobisDetector) Score(data HasValueCount) float64 {
+	model := m.model(data)
+	if model == nil {
+		return 0
+	}
+	return model.Distance(m.vector(data))
+}
+
+// Predict mengembalikan true jika jarak melewati Threshold
+func (m *MahalanobisDetector) Predict(data FeatureProvider) bool {
+	return m.Score(data) > m.Threshold
+}
+
+// Calibrate memilih Threshold sehingga sekitar falsePositiveRate bagian dari data normal dianggap anomali
+func (m *MahalanobisDetector) Calibrate(normal DataSet, falsePositiveRate float64) error {
+	threshold, err := ScoreThreshold(m, normal, falsePositiveRate)
+	if err != nil {
+		return err
+	}
+	m.Threshold = threshold
+	return nil
+}
+
+// Explain menjelaskan skor dengan menampilkan deviasi setiap feature dalam satuan standar deviasi,
+// diurutkan dari yang paling menyimpang
+func (m *MahalanobisDetector) Explain(data HasValueCount) string {
+	model := m.model(data)
+	if model == nil {
+		return ""
+	}
+
+	type deviation struct {
+		name  string
+		value float64
+		z     float64
+	}
+
+	vector := m.vector(data)
+	deviations := make([]deviation, len(vector))
+	for i, value := range vector {
+		name := fmt.Sprintf("feature %d", i)
+		if i < len(m.FeatureNames) {
+			name = m.FeatureNames[i]
+		}
+		z := 0.0
+		if variance := model.Covariance[i][i]; variance > 0 {
+			z = (value - model.Mean[i]) / math.Sqrt(variance)
+		}
+		deviations[i] = deviation{name: name, value: value, z: z}
+	}
+	sort.SliceStable(deviations, func(a, b int) bool { return math.Abs(deviations[a].z) > math.Abs(deviations[b].z) })
+
+	parts := make([]string, len(deviations))
+	for i, d := range deviations {
+		parts[i] = fmt.Sprintf("%s=%.0f (%+.1fσ)", d.name, d.value, d.z)
+	}
+	explanation := fmt.Sprintf("mahalanobis distance %.2f (threshold %.2f): %s", m.Score(data), m.Threshold, strings.Join(parts, ", "))
+	if m.GroupFeature >= 0 && model == m.Global {
+		explanation += fmt.Sprintf(" [group %d not seen in training, using global model]", data.GetFeatureValue(m.GroupFeature))
+	}
+	return explanation
+}
+
+// SaveModel menyimpan detector ke file JSON
+func (m *MahalanobisDetector) SaveModel(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	return encoder.Encode(m)
+}
+
+// LoadMahalanobisDetector membaca detector dari file JSON
+func LoadMahalanobisDetector(filename string) (*MahalanobisDetector, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var detector MahalanobisDetector
+	if err := json.NewDecoder(file).Decode(&detector); err != nil {
+		return nil, err
+	}
+	if detector.Global == nil {
+		return nil, fmt.Errorf("mahalanobis detector %s has no model", filename)
+	}
+	return &detector, nil
+}
@@ -0,0 +1,44 @@
+package ml
+
+import "testing"
+
+// groupedData membuat data dua feature dengan count sampel untuk setiap grup pada feature ketiga
+func groupedData(counts map[int]int) DataSet {
+	var dataset DataSet
+	for group, count := range counts {
+		for i := 0; i < count; i++ {
+			dataset = append(dataset, WindowSample{Record: Record{
+				Names:  []string{"x", "y", "group"},
+				Values: []int{group*100 + i%7, group*50 + i%5, group},
+			}})
+		}
+	}
+	return dataset
+}
+
+func TestMahalanobisSmallGroupsUseGlobal(t *testing.T) {
+	dataset := groupedData(map[int]int{1: 40, 2: 19, 3: 20})
+
+	detector := NewMahalanobisDetector()
+	detector.Features = []int{0, 1}
+	detector.GroupFeature = 2
+	if err := detector.Train(dataset); err != nil {
+		t.Fatal(err)
+	}
+
+	// Default 10 kali jumlah feature: grup dengan kurang dari 20 data memakai Global
+	if detector.Groups[1] == nil || detector.Groups[3] == nil {
+		t.Errorf("groups with enough data must have their own model: %v", detector.Groups)
+	}
+	if _, exists := detector.Groups[2]; exists {
+		t.Error("group with 19 samples must fall back to the global model")
+	}
+
+	detector.MinGroupSize = 30
+	if err := detector.Train(dataset); err != nil {
+		t.Fatal(err)
+	}
+	if len(detector.Groups) != 1 || detector.Groups[1] == nil {
+		t.Errorf("MinGroupSize 30 must keep only group 1, got %v", detector.Groups)
+	}
+}
@@ -0,0 +1,370 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// MatrixProfileOptions mengatur perhitungan matrix profile
+type MatrixProfileOptions struct {
+	WindowLength int // panjang subsequence dalam sampel
+
+	// ZNormalize membandingkan bentuk subsequence tanpa memperhatikan level dan skala.
+	// Jika false, setiap feature dibagi standar deviasi globalnya lalu dibandingkan apa adanya,
+	// cocok untuk mencari segmen yang di-replay persis
+	ZNormalize bool
+
+	// ExclusionZone mengabaikan tetangga yang terlalu dekat (trivial match), default WindowLength/2
+	ExclusionZone int
+}
+
+// MatrixProfile adalah jarak setiap subsequence ke tetangga terdekatnya (bukan trivial match).
+// Untuk beberapa feature jarak kuadratnya dijumlahkan
+type MatrixProfile struct {
+	Features     []string
+	WindowLength int
+	ZNormalize   bool
+	Exclusion    int
+
+	Profile    []float64 // jarak ke tetangga terdekat, index = awal subsequence
+	Index      []int     // awal subsequence tetangga terdekat
+	Timestamps []float64 // timestamp awal setiap subsequence
+
+	series [][]float64
+	means  [][]float64
+	stds   [][]float64
+}
+
+// Discord adalah subsequence yang paling jauh dari semua subsequence lain
+type Discord struct {
+	Index     int
+	Timestamp float64
+	Distance  float64
+	Neighbor  int
+}
+
+// Motif adalah pasangan subsequence yang paling mirip beserta semua kemunculan pola tersebut
+type Motif struct {
+	Index       int
+	Neighbor    int
+	Timestamps  [2]float64
+	Distance    float64
+	Occurrences []int // awal subsequence yang jaraknya ke Index masih dalam radius
+}
+
+// ComputeMatrixProfile menghitung matrix profile dengan STOMP: dot product setiap baris
+// diturunkan dari baris sebelumnya sehingga totalnya O(n²) per feature
+func ComputeMatrixProfile(sequence []SequentialProvider, features []string, options MatrixProfileOptions) (*MatrixProfile, error) {
+	m := options.WindowLength
+	if m < 2 {
+		return nil, fmt.Errorf("window length must be at least 2, got %d", m)
+	}
+	if len(features) == 0 {
+		return nil, fmt.Errorf("at least one feature is required")
+	}
+
+	exclusion := options.ExclusionZone
+	if exclusion <= 0 {
+		exclusion = max(m/2, 1)
+	}
+
+	count := len(sequence) - m + 1
+	if count <= exclusion {
+		return nil, fmt.Errorf("sequence of %d samples is too short for window length %d", len(sequence), m)
+	}
+
+	mp := &MatrixProfile{
+		Features:     features,
+		WindowLength: m,
+		ZNormalize:   options.ZNormalize,
+		Exclusion:    exclusion,
+		Profile:      make([]float64, count),
+		Index:        make([]int, count),
+		Timestamps:   make([]float64, count),
+	}
+	for i := 0; i < count; i++ {
+		mp.Profile[i] = math.Inf(1)
+		mp.Index[i] = -1
+		mp.Timestamps[i] = sequence[i].GetTimestamp()
+	}
+
+	if err := mp.prepare(sequence); err != nil {
+		return nil, err
+	}
+
+	// Baris pertama dihitung langsung dan disimpan untuk kolom pertama baris berikutnya
+	qt := make([][]float64, len(features))
+	firstRow := make([][]float64, len(features))
+	for f := range features {
+		qt[f] = make([]float64, count)
+		for j := 0; j < count; j++ {
+			qt[f][j] = mp.dot(f, 0, j)
+		}
+		firstRow[f] = append([]float64(nil), qt[f]...)
+	}
+
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			for f, t := range mp.series {
+				for j := count - 1; j >= 1; j-- {
+					qt[f][j] = qt[f][j-1] - t[i-1]*t[j-1] + t[i+m-1]*t[j+m-1]
+				}
+				qt[f][0] = firstRow[f][i]
+			}
+		}
+
+		// Matriks jarak simetris, cukup hitung segitiga atas
+		for j := i + exclusion; j < count; j++ {
+			var d2 float64
+			for f := range mp.series {
+				d2 += mp.distance2(f, i, j, qt[f][j])
+			}
+			if d2 < mp.Profile[i] {
+				mp.Profile[i], mp.Index[i] = d2, j
+			}
+			if d2 < mp.Profile[j] {
+				mp.Profile[j], mp.Index[j] = d2, i
+			}
+		}
+	}
+
+	for i := range mp.Profile {
+		mp.Profile[i] = math.Sqrt(mp.Profile[i])
+	}
+	return mp, nil
+}
+
+// Baca nilai feature, hitung mean dan standar deviasi setiap subsequence
+func (mp *MatrixProfile) prepare(sequence []SequentialProvider) error {
+	m, count := mp.WindowLength, len(mp.Profile)
+
+	mp.series = make([][]float64, len(mp.Features))
+	mp.means = make([][]float64, len(mp.Features))
+	mp.stds = make([][]float64, len(mp.Features))
+
+	for f, feature := range mp.Features {
+		series := make([]float64, len(sequence))
+		for t, data := range sequence {
+			value, exists := FeatureValueByName(data, feature)
+			if !exists {
+				return fmt.Errorf("feature not found in data: %s", feature)
+			}
+			series[t] = value
+		}
+
+		// Tanpa z-normalization feature disamakan skalanya dengan standar deviasi global
+		if !mp.ZNormalize {
+			if _, stdDev := meanStdDev(series); stdDev > 0 {
+				for t := range series {
+					series[t] /= stdDev
+				}
+			}
+		}
+		mp.series[f] = series
+
+		mp.means[f] = make([]float64, count)
+		mp.stds[f] = make([]float64, count)
+		var sum, sumSq float64
+		for t := 0; t < len(series); t++ {
+			sum += series[t]
+			sumSq += series[t] * series[t]
+			if t >= m {
+				sum -= series[t-m]
+				sumSq -= series[t-m] * series[t-m]
+			}
+			if t >= m-1 {
+				mean := sum / float64(m)
+				mp.means[f][t-m+1] = mean
+				mp.stds[f][t-m+1] = math.Sqrt(math.Max(sumSq/float64(m)-mean*mean, 0))
+			}
+		}
+	}
+	return nil
+}
+
+func (mp *MatrixProfile) dot(f, i, j int) float64 {
+	var sum float64
+	for k := 0; k < mp.WindowLength; k++ {
+		sum += mp.series[f][i+k] * mp.series[f][j+k]
+	}
+	return sum
+}
+
+// Jarak kuadrat subsequence i dan j untuk satu feature dari dot product-nya
+func (mp *MatrixProfile) distance2(f, i, j int, qt float64) float64 {
+	m := float64(mp.WindowLength)
+	meanI, meanJ := mp.means[f][i], mp.means[f][j]
+	stdI, stdJ := mp.stds[f][i], mp.stds[f][j]
+
+	if !mp.ZNormalize {
+		// Σ(a-b)² = Σa² + Σb² - 2Σab, dengan Σa² = m(σ² + μ²)
+		sumSqI := m * (stdI*stdI + meanI*meanI)
+		sumSqJ := m * (stdJ*stdJ + meanJ*meanJ)
+		return math.Max(sumSqI+sumSqJ-2*qt, 0)
+	}
+
+	// Subsequence konstan (misalnya speed 0 saat parkir) tidak bisa di-z-normalize
+	const epsilon = 1e-9
+	switch {
+	case stdI < epsilon && stdJ < epsilon:
+		return 0
+	case stdI < epsilon || stdJ < epsilon:
+		return m
+	}
+
+	correlation := (qt - m*meanI*meanJ) / (m * stdI * stdJ)
+	return math.Max(2*m*(1-correlation), 0)
+}
+
+// Distance menghitung jarak dua subsequence secara langsung
+func (mp *MatrixProfile) Distance(i, j int) float64 {
+	var d2 float64
+	for f := range mp.series {
+		d2 += mp.distance2(f, i, j, mp.dot(f, i, j))
+	}
+	return math.Sqrt(d2)
+}
+
+// Discords mengembalikan k subsequence paling tidak biasa yang tidak saling tumpang tindih
+func (mp *MatrixProfile) Discords(k int) []Discord {
+	order := mp.sortedIndexes(true)
+
+	var discords []Discord
+	taken := make([]bool, len(mp.Profile))
+	for _, i := range order {
+		if len(discords) >= k {
+			break
+		}
+		if taken[i] || math.IsInf(mp.Profile[i], 1) {
+			continue
+		}
+
+		discords = append(discords, Discord{
+			Index:     i,
+			Timestamp: mp.Timestamps[i],
+			Distance:  mp.Profile[i],
+			Neighbor:  mp.Index[i],
+		})
+		mp.markTaken(taken, i)
+	}
+	return discords
+}
+
+// Motifs mengembalikan k pasangan paling mirip yang tidak saling tumpang tindih.
+// Kemunculan lain dihitung jika jaraknya ke motif paling jauh radius kali jarak pasangan motif,
+// sehingga motif berjarak 0 (misalnya segmen hasil replay) hanya mencakup salinan yang identik
+func (mp *MatrixProfile) Motifs(k int, radius float64) []Motif {
+	order := mp.sortedIndexes(false)
+
+	var motifs []Motif
+	taken := make([]bool, len(mp.Profile))
+	for _, i := range order {
+		if len(motifs) >= k {
+			break
+		}
+		j := mp.Index[i]
+		if taken[i] || j < 0 || taken[j] {
+			continue
+		}
+
+		motif := Motif{
+			Index:      i,
+			Neighbor:   j,
+			Timestamps: [2]float64{mp.Timestamps[i], mp.Timestamps[j]},
+			Distance:   mp.Profile[i],
+		}
+
+		limit := radius*motif.Distance + 1e-9
+		for other := range mp.Profile {
+			if taken[other] || (other != i && abs(other-i) < mp.Exclusion) {
+				continue
+			}
+			if other == i || mp.Distance(i, other) <= limit {
+				// Kemunculan yang tumpang tindih dengan kemunculan sebelumnya dilewati
+				if n := len(motif.Occurrences); n > 0 && other-motif.Occurrences[n-1] < mp.Exclusion {
+					continue
+				}
+				motif.Occurrences = append(motif.Occurrences, other)
+			}
+		}
+
+		for _, occurrence := range motif.Occurrences {
+			mp.markTaken(taken, occurrence)
+		}
+		mp.markTaken(taken, j)
+		motifs = append(motifs, motif)
+	}
+	return motifs
+}
+
+// Index profile terurut, descending untuk discord dan ascending untuk motif
+func (mp *MatrixProfile) sortedIndexes(descending bool) []int {
+	order := make([]int, len(mp.Profile))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if descending {
+			return mp.Profile[order[a]] > mp.Profile[order[b]]
+		}
+		return mp.Profile[order[a]] < mp.Profile[order[b]]
+	})
+	return order
+}
+
+// Tandai subsequence yang tumpang tindih dengan subsequence i
+func (mp *MatrixProfile) markTaken(taken []bool, i int) {
+	for t := max(i-mp.WindowLength+1, 0); t < min(i+mp.WindowLength, len(taken)); t++ {
+		taken[t] = true
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// MatrixProfileReport berisi discord dan motif untuk forensik rekaman panjang
+type MatrixProfileReport struct {
+	Features     []string
+	WindowLength int
+	Discords     []Discord
+	Motifs       []Motif
+}
+
+// Report membuat laporan k discord dan k motif
+func (mp *MatrixProfile) Report(k int, radius float64) *MatrixProfileReport {
+	return &MatrixProfileReport{
+		Features:     mp.Features,
+		WindowLength: mp.WindowLength,
+		Discords:     mp.Discords(k),
+		Motifs:       mp.Motifs(k, radius),
+	}
+}
+
+func (r *MatrixProfileReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Features: %s, window length %d\n", strings.Join(r.Features, ", "), r.WindowLength)
+
+	fmt.Fprintf(&sb, "Discords:\n")
+	for rank, discord := range r.Discords {
+		fmt.Fprintf(&sb, "%d. t=%.3f (sample %d) distance=%.3f nearest=sample %d\n",
+			rank+1, discord.Timestamp, discord.Index, discord.Distance, discord.Neighbor)
+	}
+
+	fmt.Fprintf(&sb, "Motifs:\n")
+	for rank, motif := range r.Motifs {
+		fmt.Fprintf(&sb, "%d. t=%.3f and t=%.3f (samples %d, %d) distance=%.3f occurrences=%d %v\n",
+			rank+1, motif.Timestamps[0], motif.Timestamps[1], motif.Index, motif.Neighbor,
+			motif.Distance, len(motif.Occurrences), motif.Occurrences)
+	}
+	return sb.String()
+}
+
+func (r *MatrixProfileReport) PrintReport() {
+	fmt.Print(r.String())
+}
@@ -0,0 +1,94 @@
+package ml
+
+import (
+	"math"
+	"testing"
+)
+
+// periodicSequence mengulang pola speed yang sama, dengan segmen lain mulai di anomalyAt
+func periodicSequence(periods, anomalyAt int) []SequentialProvider {
+	pattern := []int{0, 2, 4, 6, 4, 2}
+	anomaly := []int{0, 9, 0, 9, 0, 9}
+
+	var sequence []SequentialProvider
+	for i := 0; i < periods*len(pattern); i++ {
+		speed := pattern[i%len(pattern)]
+		if i >= anomalyAt && i < anomalyAt+len(anomaly) {
+			speed = anomaly[i-anomalyAt]
+		}
+		sequence = append(sequence, testRecord(float64(i), 2000, 3, speed))
+	}
+	return sequence
+}
+
+// naiveProfile menghitung matrix profile satu feature langsung dari definisinya
+func naiveProfile(values []float64, m, exclusion int, zNormalize bool) []float64 {
+	normalize := func(sub []float64) []float64 {
+		mean, std := meanStdDev(sub)
+		out := make([]float64, len(sub))
+		for i, v := range sub {
+			if zNormalize {
+				out[i] = (v - mean) / std
+			} else {
+				out[i] = v
+			}
+		}
+		return out
+	}
+
+	count := len(values) - m + 1
+	profile := make([]float64, count)
+	for i := range profile {
+		profile[i] = math.Inf(1)
+		a := normalize(values[i : i+m])
+		for j := 0; j < count; j++ {
+			if abs(i-j) < exclusion {
+				continue
+			}
+			b := normalize(values[j : j+m])
+			var d2 float64
+			for k := range a {
+				d2 += (a[k] - b[k]) * (a[k] - b[k])
+			}
+			profile[i] = min(profile[i], math.Sqrt(d2))
+		}
+	}
+	return profile
+}
+
+func TestMatrixProfileKnownAnswer(t *testing.T) {
+	sequence := periodicSequence(10, 30)
+	values := make([]float64, len(sequence))
+	for i, data := range sequence {
+		values[i], _ = FeatureValueByName(data, "speed")
+	}
+
+	for _, zNormalize := range []bool{false, true} {
+		mp, err := ComputeMatrixProfile(sequence, []string{"speed"}, MatrixProfileOptions{WindowLength: 6, ZNormalize: zNormalize})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Tanpa z-normalization feature dibagi standar deviasi globalnya, jarak ikut terbagi
+		want := naiveProfile(values, 6, mp.Exclusion, zNormalize)
+		scale := 1.0
+		if !zNormalize {
+			_, std := meanStdDev(values)
+			scale = 1 / std
+		}
+		for i := range want {
+			if math.Abs(mp.Profile[i]-want[i]*scale) > 1e-6 {
+				t.Fatalf("znormalize=%v: profile[%d] = %v, want %v", zNormalize, i, mp.Profile[i], want[i]*scale)
+			}
+		}
+
+		// Segmen yang berulang punya tetangga identik, segmen anomali menjadi discord
+		if mp.Profile[0] > 1e-6 {
+			t.Errorf("znormalize=%v: repeated segment has distance %v", zNormalize, mp.Profile[0])
+		}
+		discords := mp.Discords(1)
+		if len(discords) != 1 || discords[0].Index < 30-5 || discords[0].Index > 30+5 {
+			t.Errorf("znormalize=%v: discord %+v, want near index 30", zNormalize, discords)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// NeighborMethod adalah cara NeighborDetector menghitung skor
+type NeighborMethod string
+
+const (
+	// NeighborKNN memakai jarak ke tetangga ke-k, makin jauh makin anomali
+	NeighborKNN NeighborMethod = "knn"
+
+	// NeighborLOF memakai Local Outlier Factor, perbandingan kepadatan data dengan kepadatan tetangganya.
+	// Skor sekitar 1 berarti normal, jauh di atas 1 berarti anomali
+	NeighborLOF NeighborMethod = "lof"
+)
+
+// NeighborDetector mendeteksi anomali berdasarkan kepadatan data normal.
+// Cocok untuk anomali berupa kombinasi nilai yang jarang, bukan nilai yang melewati batas.
+// IsAnomaly data latih diabaikan, jadi sebaiknya dilatih dengan data yang sebagian besar normal
+type NeighborDetector struct {
+	Method    NeighborMethod
+	K         int
+	Scaler    *FeatureScaler
+	Points    [][]float64 // data latih yang sudah diskalakan
+	Threshold float64
+
+	// Untuk LOF: jarak ke tetangga ke-k dan local reachability density setiap data latih
+	KDistance []float64 `json:",omitempty"`
+	Density   []float64 `json:",omitempty"`
+
+	tree *KDTree
+}
+
+// TrainKNNDetector melatih detector jarak k-NN
+func TrainKNNDetector(dataset DataSet, k int) (*NeighborDetector, error) {
+	return trainNeighborDetector(NeighborKNN, dataset, k)
+}
+
+// TrainLOFDetector melatih detector Local Outlier Factor
+func TrainLOFDetector(dataset DataSet, k int) (*NeighborDetector, error) {
+	return trainNeighborDetector(NeighborLOF, dataset, k)
+}
+
+func trainNeighborDetector(method NeighborMethod, dataset DataSet, k int) (*NeighborDetector, error) {
+	if k < 1 {
+		return nil, fmt.Errorf("k must be at least 1, got %d", k)
+	}
+	if len(dataset) <= k {
+		return nil, fmt.Errorf("dataset needs more than %d samples, got %d", k, len(dataset))
+	}
+
+	scaler, err := FitScaler(dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	detector := &NeighborDetector{Method: method, K: k, Scaler: scaler}
+	detector.Points = make([][]float64, len(dataset))
+	for i, data := range dataset {
+		detector.Points[i] = scaler.Transform(data)
+	}
+
+	if err := detector.init(); err != nil {
+		return nil, err
+	}
+
+	// Threshold awal: skor tertinggi di data latih, bisa diubah dengan SetContamination
+	for i := range detector.Points {
+		detector.Threshold = max(detector.Threshold, detector.pointScore(detector.Points[i], i))
+	}
+	return detector, nil
+}
+
+// init membangun KD-tree dan, untuk LOF, menghitung k-distance dan density data latih
+func (d *NeighborDetector) init() error {
+	switch d.Method {
+	case NeighborKNN, NeighborLOF:
+	default:
+		return fmt.Errorf("unknown neighbor method: %s", d.Method)
+	}
+	if d.Scaler == nil || len(d.Points) <= d.K {
+		return fmt.Errorf("neighbor detector needs a scaler and more than %d points", d.K)
+	}
+
+	d.tree = NewKDTree(d.Points)
+	if d.Method != NeighborLOF || len(d.Density) == len(d.Points) {
+		return nil
+	}
+
+	neighbors := make([][]Neighbor, len(d.Points))
+	d.KDistance = make([]float64, len(d.Points))
+	for i, point := range d.Points {
+		neighbors[i] = d.tree.KNearest(point, d.K, i)
+		d.KDistance[i] = neighbors[i][len(neighbors[i])-1].Distance
+	}
+
+	d.Density = make([]float64, len(d.Points))
+	for i := range d.Points {
+		d.Density[i] = d.reachabilityDensity(neighbors[i])
+	}
+	return nil
+}
+
+// Local reachability density: kebalikan rata-rata reachability distance ke tetangga
+func (d *NeighborDetector) reachabilityDensity(neighbors []Neighbor) float64 {
+	var sum float64
+	for _, n := range neighbors {
+		sum += max(d.KDistance[n.Index], n.Distance)
+	}
+	// Epsilon mencegah density tak hingga untuk data duplikat
+	return 1 / (sum/float64(len(neighbors)) + 1e-10)
+}
+
+// skip adalah index data latih yang sedang diskor, -1 untuk data baru
+func (d *NeighborDetector) pointScore(point []float64, skip int) float64 {
+	neighbors := d.tree.KNearest(point, d.K, skip)
+	if d.Method == NeighborKNN {
+		return neighbors[len(neighbors)-1].Distance
+	}
+
+	var sum float64
+	for _, n := range neighbors {
+		sum += d.Density[n.Index]
+	}
+	return sum / float64(len(neighbors)) / d.reachabilityDensity(neighbors)
+}
+
+// Score mengembalikan skor k-NN atau LOF untuk data baru
+func (d *NeighborDetector) Score(data HasValueCount) float64 {
+	if d.tree == nil {
+		if err := d.init(); err != nil {
+			return 0
+		}
+	}
+	return d.pointScore(d.Scaler.Transform(data), -1)
+}
+
+// Predict mengembalikan true jika skor melewati Threshold
+func (d *NeighborDetector) Predict(data FeatureProvider) bool {
+	return d.Score(data) > d.Threshold
+}
+
+// SetContamination memilih Threshold sehingga sekitar rate bagian dari dataset dianggap anomali
+func (d *NeighborDetector) SetContamination(dataset DataSet, rate float64) error {
+	threshold, err := ScoreThreshold(d, dataset, rate)
+	if err != nil {
+		return err
+	}
+	d.Threshold = threshold
+	return nil
+}
+
+// SaveModel menyimpan detector ke file JSON, KD-tree dibangun ulang saat dibaca
+func (d *NeighborDetector) SaveModel(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	return encoder.Encode(d)
+}
+
+// LoadNeighborDetector membaca detector dari file JSON
+func LoadNeighborDetector(filename string) (*NeighborDetector, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var detector NeighborDetector
+	if err := json.NewDecoder(file).Decode(&detector); err != nil {
+		return nil, err
+	}
+	if err := detector.init(); err != nil {
+		return nil, err
+	}
+	return &detector, nil
+}
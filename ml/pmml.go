@@ -0,0 +1,458 @@
+package ml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+)
+
+const (
+	pmmlVersion   = "4.4"
+	pmmlNamespace = "http://www.dmg.org/PMML-4_4"
+	pmmlTarget    = "anomaly"
+)
+
+// Struktur XML untuk dokumen PMML (hanya bagian yang dipakai oleh decision tree)
+type pmmlDocument struct {
+	XMLName        xml.Name           `xml:"PMML"`
+	Version        string             `xml:"version,attr"`
+	Xmlns          string             `xml:"xmlns,attr,omitempty"`
+	Header         pmmlHeader         `xml:"Header"`
+	DataDictionary pmmlDataDictionary `xml:"DataDictionary"`
+	TreeModel      *pmmlTreeModel     `xml:"TreeModel,omitempty"`
+	MiningModel    *pmmlMiningModel   `xml:"MiningModel,omitempty"`
+}
+
+type pmmlHeader struct {
+	Description string `xml:"description,attr,omitempty"`
+}
+
+type pmmlDataDictionary struct {
+	NumberOfFields int             `xml:"numberOfFields,attr"`
+	DataFields     []pmmlDataField `xml:"DataField"`
+}
+
+type pmmlDataField struct {
+	Name     string      `xml:"name,attr"`
+	OpType   string      `xml:"optype,attr"`
+	DataType string      `xml:"dataType,attr"`
+	Values   []pmmlValue `xml:"Value,omitempty"`
+}
+
+type pmmlValue struct {
+	Value string `xml:"value,attr"`
+}
+
+type pmmlMiningSchema struct {
+	MiningFields []pmmlMiningField `xml:"MiningField"`
+}
+
+type pmmlMiningField struct {
+	Name      string `xml:"name,attr"`
+	UsageType string `xml:"usageType,attr,omitempty"`
+}
+
+type pmmlTreeModel struct {
+	ModelName           string           `xml:"modelName,attr,omitempty"`
+	FunctionName        string           `xml:"functionName,attr"`
+	SplitCharacteristic string           `xml:"splitCharacteristic,attr,omitempty"`
+	MiningSchema        pmmlMiningSchema `xml:"MiningSchema"`
+	Node                pmmlNode         `xml:"Node"`
+}
+
+type pmmlMiningModel struct {
+	ModelName    string           `xml:"modelName,attr,omitempty"`
+	FunctionName string           `xml:"functionName,attr"`
+	MiningSchema pmmlMiningSchema `xml:"MiningSchema"`
+	Segmentation pmmlSegmentation `xml:"Segmentation"`
+}
+
+type pmmlSegmentation struct {
+	MultipleModelMethod string        `xml:"multipleModelMethod,attr"`
+	Segments            []pmmlSegment `xml:"Segment"`
+}
+
+type pmmlSegment struct {
+	ID        string        `xml:"id,attr"`
+	True      *struct{}     `xml:"True"`
+	TreeModel pmmlTreeModel `xml:"TreeModel"`
+}
+
+type pmmlNode struct {
+	ID              string               `xml:"id,attr,omitempty"`
+	Score           string               `xml:"score,attr,omitempty"`
+	True            *struct{}            `xml:"True"`
+	SimplePredicate *pmmlSimplePredicate `xml:"SimplePredicate"`
+	Nodes           []pmmlNode           `xml:"Node"`
+}
+
+type pmmlSimplePredicate struct {
+	Field    string `xml:"field,attr"`
+	Operator string `xml:"operator,attr"`
+	Value    string `xml:"value,attr"`
+}
+
+// WritePMML menulis tree sebagai PMML TreeModel.
+// featureNames dipakai untuk DataDictionary, index-nya harus sama dengan Node.Feature
+func (node *Node) WritePMML(w io.Writer, featureNames []string) error {
+	if err := node.validateFeatures(featureNames); err != nil {
+		return err
+	}
+
+	model := newPMMLTreeModel(node, featureNames, "DecisionTree")
+	doc := newPMMLDocument(featureNames)
+	doc.TreeModel = &model
+
+	return writePMMLDocument(w, doc)
+}
+
+// SavePMML menyimpan tree ke file PMML
+func (node *Node) SavePMML(filename string, featureNames []string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return node.WritePMML(file, featureNames)
+}
+
+// WriteForestPMML menulis kumpulan tree sebagai PMML MiningModel dengan majority vote
+func WriteForestPMML(w io.Writer, trees []*Node, featureNames []string) error {
+	if len(trees) == 0 {
+		return fmt.Errorf("forest has no trees")
+	}
+
+	segments := make([]pmmlSegment, 0, len(trees))
+	for i, tree := range trees {
+		if err := tree.validateFeatures(featureNames); err != nil {
+			return fmt.Errorf("tree %d: %v", i, err)
+		}
+		segments = append(segments, pmmlSegment{
+			ID:        strconv.Itoa(i + 1),
+			True:      &struct{}{},
+			TreeModel: newPMMLTreeModel(tree, featureNames, fmt.Sprintf("DecisionTree%d", i+1)),
+		})
+	}
+
+	doc := newPMMLDocument(featureNames)
+	doc.MiningModel = &pmmlMiningModel{
+		ModelName:    "DecisionForest",
+		FunctionName: "classification",
+		MiningSchema: newPMMLMiningSchema(featureNames),
+		Segmentation: pmmlSegmentation{
+			MultipleModelMethod: "majorityVote",
+			Segments:            segments,
+		},
+	}
+
+	return writePMMLDocument(w, doc)
+}
+
+// SaveForestPMML menyimpan kumpulan tree ke file PMML
+func SaveForestPMML(filename string, trees []*Node, featureNames []string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return WriteForestPMML(file, trees, featureNames)
+}
+
+// ReadPMML membaca PMML TreeModel menjadi tree.
+// Mengembalikan juga nama feature sesuai urutan DataDictionary
+func ReadPMML(r io.Reader) (*Node, []string, error) {
+	doc, featureNames, err := readPMMLDocument(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if doc.TreeModel == nil {
+		return nil, nil, fmt.Errorf("PMML does not contain a TreeModel")
+	}
+
+	root, err := doc.TreeModel.Node.toNode(featureIndexes(featureNames))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return root, featureNames, nil
+}
+
+// LoadPMML membaca tree dari file PMML
+func LoadPMML(filename string) (*Node, []string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	return ReadPMML(file)
+}
+
+// ReadForestPMML membaca PMML MiningModel (atau satu TreeModel) menjadi kumpulan tree
+func ReadForestPMML(r io.Reader) ([]*Node, []string, error) {
+	doc, featureNames, err := readPMMLDocument(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	indexes := featureIndexes(featureNames)
+
+	if doc.TreeModel != nil {
+		root, err := doc.TreeModel.Node.toNode(indexes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []*Node{root}, featureNames, nil
+	}
+
+	if doc.MiningModel == nil {
+		return nil, nil, fmt.Errorf("PMML does not contain a TreeModel or MiningModel")
+	}
+
+	trees := make([]*Node, 0, len(doc.MiningModel.Segmentation.Segments))
+	for _, segment := range doc.MiningModel.Segmentation.Segments {
+		root, err := segment.TreeModel.Node.toNode(indexes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("segment %s: %v", segment.ID, err)
+		}
+		trees = append(trees, root)
+	}
+
+	return trees, featureNames, nil
+}
+
+// LoadForestPMML membaca kumpulan tree dari file PMML
+func LoadForestPMML(filename string) ([]*Node, []string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	return ReadForestPMML(file)
+}
+
+func newPMMLDocument(featureNames []string) pmmlDocument {
+	fields := make([]pmmlDataField, 0, len(featureNames)+1)
+	for _, name := range featureNames {
+		fields = append(fields, pmmlDataField{
+			Name:     name,
+			OpType:   "continuous",
+			DataType: "integer",
+		})
+	}
+	fields = append(fields, pmmlDataField{
+		Name:     pmmlTarget,
+		OpType:   "categorical",
+		DataType: "boolean",
+		Values:   []pmmlValue{{Value: "false"}, {Value: "true"}},
+	})
+
+	return pmmlDocument{
+		Version: pmmlVersion,
+		Xmlns:   pmmlNamespace,
+		Header:  pmmlHeader{Description: "ECU anomaly decision tree"},
+		DataDictionary: pmmlDataDictionary{
+			NumberOfFields: len(fields),
+			DataFields:     fields,
+		},
+	}
+}
+
+func newPMMLMiningSchema(featureNames []string) pmmlMiningSchema {
+	fields := make([]pmmlMiningField, 0, len(featureNames)+1)
+	for _, name := range featureNames {
+		fields = append(fields, pmmlMiningField{Name: name})
+	}
+	fields = append(fields, pmmlMiningField{Name: pmmlTarget, UsageType: "target"})
+	return pmmlMiningSchema{MiningFields: fields}
+}
+
+func newPMMLTreeModel(node *Node, featureNames []string, modelName string) pmmlTreeModel {
+	id := 0
+	root := node.toPMMLNode(featureNames, nil, &id)
+	return pmmlTreeModel{
+		ModelName:           modelName,
+		FunctionName:        "classification",
+		SplitCharacteristic: "binarySplit",
+		MiningSchema:        newPMMLMiningSchema(featureNames),
+		Node:                root,
+	}
+}
+
+// Konversi Node menjadi pmmlNode secara rekursif.
+// predicate nil berarti node root (predicate True)
+func (node *Node) toPMMLNode(featureNames []string, predicate *pmmlSimplePredicate, id *int) pmmlNode {
+	result := pmmlNode{ID: strconv.Itoa(*id)}
+	*id++
+
+	if predicate == nil {
+		result.True = &struct{}{}
+	} else {
+		result.SimplePredicate = predicate
+	}
+
+	if node.IsLeaf {
+		result.Score = strconv.FormatBool(node.Prediction)
+		return result
+	}
+
+	field := featureNames[node.Feature]
+	value := strconv.Itoa(node.Threshold)
+
+	result.Nodes = []pmmlNode{
+		node.Left.toPMMLNode(featureNames, &pmmlSimplePredicate{Field: field, Operator: "lessOrEqual", Value: value}, id),
+		node.Right.toPMMLNode(featureNames, &pmmlSimplePredicate{Field: field, Operator: "greaterThan", Value: value}, id),
+	}
+
+	return result
+}
+
+// Konversi pmmlNode kembali menjadi Node.
+// Hanya mendukung binary split dengan SimplePredicate pada child pertama
+func (p pmmlNode) toNode(indexes map[string]int) (*Node, error) {
+	if len(p.Nodes) == 0 {
+		prediction, err := strconv.ParseBool(p.Score)
+		if err != nil {
+			return nil, fmt.Errorf("invalid score at node %s: %q", p.ID, p.Score)
+		}
+		return &Node{IsLeaf: true, Prediction: prediction}, nil
+	}
+
+	if len(p.Nodes) != 2 {
+		return nil, fmt.Errorf("node %s: expected 2 children, got %d", p.ID, len(p.Nodes))
+	}
+
+	first, second := p.Nodes[0], p.Nodes[1]
+	predicate := first.SimplePredicate
+	if predicate == nil {
+		return nil, fmt.Errorf("node %s: first child has no SimplePredicate", first.ID)
+	}
+
+	feature, exists := indexes[predicate.Field]
+	if !exists {
+		return nil, fmt.Errorf("node %s: unknown field %q", first.ID, predicate.Field)
+	}
+
+	value, err := strconv.ParseFloat(predicate.Value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("node %s: invalid value %q", first.ID, predicate.Value)
+	}
+
+	// Normalisasi ke bentuk "feature <= threshold" karena Node hanya memakai integer.
+	// Untuk integer x: x <= v sama dengan x <= floor(v), dan x < v sama dengan x <= ceil(v)-1
+	var threshold int
+	left, right := first, second
+	switch predicate.Operator {
+	case "lessOrEqual":
+		threshold = int(math.Floor(value))
+	case "lessThan":
+		threshold = int(math.Ceil(value)) - 1
+	case "greaterThan":
+		threshold = int(math.Floor(value))
+		left, right = second, first
+	case "greaterOrEqual":
+		threshold = int(math.Ceil(value)) - 1
+		left, right = second, first
+	default:
+		return nil, fmt.Errorf("node %s: unsupported operator %q", first.ID, predicate.Operator)
+	}
+
+	leftNode, err := left.toNode(indexes)
+	if err != nil {
+		return nil, err
+	}
+
+	rightNode, err := right.toNode(indexes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Node{
+		Feature:   feature,
+		Threshold: threshold,
+		Left:      leftNode,
+		Right:     rightNode,
+		IsLeaf:    false,
+	}, nil
+}
+
+// Pastikan semua feature yang dipakai tree punya nama
+func (node *Node) validateFeatures(featureNames []string) error {
+	if node == nil {
+		return fmt.Errorf("tree is nil")
+	}
+	if node.IsLeaf {
+		return nil
+	}
+	if node.Feature < 0 || node.Feature >= len(featureNames) {
+		return fmt.Errorf("feature %d has no name", node.Feature)
+	}
+	if err := node.Left.validateFeatures(featureNames); err != nil {
+		return err
+	}
+	return node.Right.validateFeatures(featureNames)
+}
+
+func writePMMLDocument(w io.Writer, doc pmmlDocument) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func readPMMLDocument(r io.Reader) (pmmlDocument, []string, error) {
+	var doc pmmlDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return doc, nil, err
+	}
+
+	// Field target diambil dari MiningSchema, default-nya "anomaly"
+	targets := map[string]bool{pmmlTarget: true}
+	var schema *pmmlMiningSchema
+	if doc.TreeModel != nil {
+		schema = &doc.TreeModel.MiningSchema
+	} else if doc.MiningModel != nil {
+		schema = &doc.MiningModel.MiningSchema
+	}
+	if schema != nil {
+		for _, field := range schema.MiningFields {
+			if field.UsageType == "target" || field.UsageType == "predicted" {
+				targets[field.Name] = true
+			}
+		}
+	}
+
+	// Semua field kecuali target dianggap feature sesuai urutan
+	var featureNames []string
+	for _, field := range doc.DataDictionary.DataFields {
+		if targets[field.Name] {
+			continue
+		}
+		featureNames = append(featureNames, field.Name)
+	}
+
+	return doc, featureNames, nil
+}
+
+func featureIndexes(featureNames []string) map[string]int {
+	indexes := make(map[string]int, len(featureNames))
+	for i, name := range featureNames {
+		indexes[name] = i
+	}
+	return indexes
+}
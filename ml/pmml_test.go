@@ -0,0 +1,67 @@
+package ml
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// pmmlSplit membuat PMML dengan satu split pada feature x.
+// Child pertama memakai operator dan nilai yang diberikan dan memprediksi anomali
+func pmmlSplit(operator, value string) string {
+	return fmt.Sprintf(`<PMML version="4.4" xmlns="http://www.dmg.org/PMML-4_4">
+  <DataDictionary numberOfFields="2">
+    <DataField name="x" optype="continuous" dataType="integer"/>
+    <DataField name="anomaly" optype="categorical" dataType="boolean"/>
+  </DataDictionary>
+  <TreeModel functionName="classification">
+    <MiningSchema>
+      <MiningField name="x"/>
+      <MiningField name="anomaly" usageType="target"/>
+    </MiningSchema>
+    <Node id="0">
+      <True/>
+      <Node id="1" score="true"><SimplePredicate field="x" operator="%s" value="%s"/></Node>
+      <Node id="2" score="false"><True/></Node>
+    </Node>
+  </TreeModel>
+</PMML>`, operator, value)
+}
+
+func TestReadPMMLThresholds(t *testing.T) {
+	tests := []struct {
+		operator string
+		value    string
+		matches  func(x int) bool
+	}{
+		{"lessOrEqual", "2.5", func(x int) bool { return float64(x) <= 2.5 }},
+		{"lessThan", "2.5", func(x int) bool { return float64(x) < 2.5 }},
+		{"greaterThan", "2.5", func(x int) bool { return float64(x) > 2.5 }},
+		{"greaterOrEqual", "2.5", func(x int) bool { return float64(x) >= 2.5 }},
+		{"lessOrEqual", "-2.5", func(x int) bool { return float64(x) <= -2.5 }},
+		{"lessThan", "-2.5", func(x int) bool { return float64(x) < -2.5 }},
+		{"greaterThan", "-2.5", func(x int) bool { return float64(x) > -2.5 }},
+		{"greaterOrEqual", "-2.5", func(x int) bool { return float64(x) >= -2.5 }},
+		{"lessOrEqual", "3", func(x int) bool { return x <= 3 }},
+		{"lessThan", "3", func(x int) bool { return x < 3 }},
+		{"greaterThan", "-3", func(x int) bool { return x > -3 }},
+		{"greaterOrEqual", "-3", func(x int) bool { return x >= -3 }},
+	}
+
+	for _, test := range tests {
+		tree, names, err := ReadPMML(strings.NewReader(pmmlSplit(test.operator, test.value)))
+		if err != nil {
+			t.Fatalf("%s %s: %v", test.operator, test.value, err)
+		}
+		if len(names) != 1 || names[0] != "x" {
+			t.Fatalf("%s %s: feature names %v", test.operator, test.value, names)
+		}
+
+		for x := -6; x <= 6; x++ {
+			data := Record{Names: names, Values: []int{x}}
+			if got, want := tree.Score(data) == 1, test.matches(x); got != want {
+				t.Errorf("x %s %s: x=%d predicted %v, want %v", test.operator, test.value, x, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package ml
+
+// RecordComparator membandingkan record lengkap sehingga bisa melihat feature lain,
+// misalnya rule RPM yang bergantung pada gear saat ini
+type RecordComparator interface {
+	// CompareRecords menerima record sebelumnya, record saat ini dan seluruh window
+	// (paling lama di depan). Mengembalikan skor dan deskripsi rule yang terpicu
+	CompareRecords(prev, current SequentialProvider, window []SequentialProvider) (float64, string)
+}
+
+// ErrorComparator adalah RecordComparator yang bisa gagal, misalnya karena feature yang dibutuhkan
+// tidak ada di data. WindowDetector mencatat error di FeatureResult.Error dan menganggap comparator
+// tidak terpicu, sama seperti rule bernama yang gagal. CompareRecords mengembalikan skor 0 jika gagal
+type ErrorComparator interface {
+	RecordComparator
+	CompareRecordsWithError(prev, current SequentialProvider, window []SequentialProvider) (float64, string, error)
+}
+
+// FeatureComparatorAdapter membuat FeatureComparator lama bisa dipakai sebagai RecordComparator
+type FeatureComparatorAdapter struct {
+	Feature    string
+	Comparator FeatureComparator
+}
+
+func (a FeatureComparatorAdapter) CompareRecords(prev, current SequentialProvider, window []SequentialProvider) (float64, string) {
+	prevValue, prevExists := FeatureValueByName(prev, a.Feature)
+	currentValue, currentExists := FeatureValueByName(current, a.Feature)
+	if !prevExists || !currentExists {
+		return 0, ""
+	}
+
+	if rc, ok := a.Comparator.(ReasonComparator); ok {
+		return rc.CompareWithReason(prevValue, currentValue)
+	}
+	return a.Comparator.Compare(prevValue, currentValue), ""
+}
+
+// FeatureIndexByName mencari index feature berdasarkan nama, -1 jika tidak ada
+func FeatureIndexByName(data SequentialProvider, name string) int {
+	for i := 0; i < data.GetFeatureCount(); i++ {
+		if data.GetFeatureName(i) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// FeatureValueByName mengambil nilai feature berdasarkan nama
+func FeatureValueByName(data SequentialProvider, name string) (float64, bool) {
+	index := FeatureIndexByName(data, name)
+	if index == -1 {
+		return 0, false
+	}
+	return float64(data.GetFeatureValue(index)), true
+}
+
+// ElapsedSeconds menghitung selisih waktu dua sampel dalam detik.
+// Jika timestamp tidak valid (sama atau mundur) dianggap 1 detik
+func ElapsedSeconds(prev, current SequentialProvider) float64 {
+	elapsed := current.GetTimestamp() - prev.GetTimestamp()
+	if elapsed <= 0 {
+		return 1
+	}
+	return elapsed
+}
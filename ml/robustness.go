@@ -0,0 +1,144 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// PerturbationResult adalah hasil analisis satu sampel yang diprediksi anomali
+type PerturbationResult struct {
+	Index        int   // index sampel di dataset
+	Perturbation []int // perubahan minimum per feature agar diprediksi normal
+	Scale        float64
+	Flippable    bool // true jika perturbation masih di dalam epsilon
+}
+
+// RobustnessReport merangkum ketahanan tree terhadap perturbasi terbatas
+type RobustnessReport struct {
+	Epsilon   []int
+	Total     int // jumlah seluruh sampel
+	Flagged   int // jumlah sampel yang diprediksi anomali
+	Flippable int // jumlah sampel flagged yang bisa dibuat normal
+	Results   []PerturbationResult
+}
+
+// Robustness mengembalikan persentase sampel flagged yang tidak bisa dibuat normal
+func (r *RobustnessReport) Robustness() float64 {
+	if r.Flagged == 0 {
+		return 100
+	}
+	return float64(r.Flagged-r.Flippable) / float64(r.Flagged) * 100
+}
+
+func (r *RobustnessReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Epsilon: %v\n", r.Epsilon)
+	fmt.Fprintf(&sb, "Flagged: %d of %d samples\n", r.Flagged, r.Total)
+	fmt.Fprintf(&sb, "Flippable: %d samples\n", r.Flippable)
+	fmt.Fprintf(&sb, "Robustness: %.2f%%\n", r.Robustness())
+	for _, result := range r.Results {
+		if !result.Flippable {
+			continue
+		}
+		fmt.Fprintf(&sb, "- sample %d: perturbation=%v scale=%.2f\n",
+			result.Index, result.Perturbation, result.Scale)
+	}
+	return sb.String()
+}
+
+func (r *RobustnessReport) PrintReport() {
+	fmt.Print(r.String())
+}
+
+// MinimumPerturbation mencari perubahan terkecil agar data diprediksi normal.
+// Jarak diukur dengan max(|delta[f]| / epsilon[f]), sehingga Scale <= 1 berarti
+// perturbation masih di dalam epsilon. Mengembalikan false jika tree tidak punya leaf normal
+func (node *Node) MinimumPerturbation(data HasValueCount, epsilon []int) ([]int, float64, bool) {
+	var rules []Rule
+	node.collectRules(map[int]RuleCondition{}, &rules)
+
+	var best []int
+	bestScale := math.Inf(1)
+	found := false
+
+	for _, rule := range rules {
+		if rule.Prediction || rule.isEmpty() {
+			continue
+		}
+
+		delta := make([]int, data.GetFeatureCount())
+		scale := 0.0
+		for _, c := range rule.Conditions {
+			if c.Feature < 0 || c.Feature >= len(delta) {
+				continue
+			}
+
+			value := data.GetFeatureValue(c.Feature)
+			d := 0
+			if c.HasLower && value <= c.Lower {
+				d = c.Lower + 1 - value
+			}
+			if c.HasUpper && value > c.Upper {
+				d = c.Upper - value
+			}
+			delta[c.Feature] = d
+
+			scale = math.Max(scale, perturbationScale(d, epsilon, c.Feature))
+		}
+
+		if !found || scale < bestScale {
+			best = delta
+			bestScale = scale
+			found = true
+		}
+	}
+
+	return best, bestScale, found
+}
+
+func perturbationScale(delta int, epsilon []int, feature int) float64 {
+	if delta == 0 {
+		return 0
+	}
+
+	eps := 0
+	if feature < len(epsilon) {
+		eps = epsilon[feature]
+	}
+	if eps <= 0 {
+		return math.Inf(1)
+	}
+
+	return math.Abs(float64(delta)) / float64(eps)
+}
+
+// AnalyzeRobustness menghitung sampel flagged mana yang bisa dibuat normal
+// dengan perturbasi |delta[f]| <= epsilon[f]
+func (node *Node) AnalyzeRobustness(dataset DataSet, epsilon []int) *RobustnessReport {
+	report := &RobustnessReport{
+		Epsilon: epsilon,
+		Total:   len(dataset),
+	}
+
+	for i, data := range dataset {
+		if !node.Predict(data) {
+			continue
+		}
+		report.Flagged++
+
+		result := PerturbationResult{Index: i, Scale: math.Inf(1)}
+		if delta, scale, found := node.MinimumPerturbation(data, epsilon); found {
+			result.Perturbation = delta
+			result.Scale = scale
+			result.Flippable = scale <= 1
+		}
+
+		if result.Flippable {
+			report.Flippable++
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	return report
+}
@@ -0,0 +1,665 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ExpressionRule adalah rule bernama yang ditulis dalam bahasa ekspresi sederhana, contoh:
+//
+//	gear > 1 && rpm < 1500 => "should downshift"
+//	abs(delta(speed)) > 5
+//
+// Nama feature menghasilkan nilai record saat ini. Fungsi yang tersedia:
+//   - abs(x), min(a, b), max(a, b)
+//   - prev(f), delta(f), rate(f) untuk record sebelumnya, selisih dan selisih per detik
+//   - mean(f), stddev(f), wmin(f), wmax(f), slope(f), zscore(f) untuk statistik window
+//   - elapsed() untuk selisih waktu dengan record sebelumnya dalam detik
+//
+// Operator: || && ! == != < <= > >= + - * / dan tanda kurung.
+// Nilai boolean direpresentasikan sebagai 1 dan 0
+type ExpressionRule struct {
+	Name       string
+	Expression string
+	Severity   Severity
+
+	message string
+	root    exprNode
+}
+
+// CompileExpressionRule mem-parsing ekspresi menjadi rule yang siap dievaluasi
+func CompileExpressionRule(name, expression string, severity Severity) (*ExpressionRule, error) {
+	rule := &ExpressionRule{
+		Name:       name,
+		Expression: expression,
+		Severity:   severity,
+	}
+	if err := rule.compile(); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *ExpressionRule) compile() error {
+	p, err := newExprParser(r.Expression)
+	if err != nil {
+		return fmt.Errorf("rule %s: %v", r.Name, err)
+	}
+
+	root, message, err := p.parseRule()
+	if err != nil {
+		return fmt.Errorf("rule %s: %v", r.Name, err)
+	}
+
+	r.root = root
+	r.message = message
+	if r.message == "" {
+		r.message = r.Name
+	}
+	return nil
+}
+
+// UnmarshalJSON membaca rule dari JSON dan langsung meng-compile ekspresinya
+func (r *ExpressionRule) UnmarshalJSON(data []byte) error {
+	type plain ExpressionRule
+	var decoded plain
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	*r = ExpressionRule(decoded)
+	return r.compile()
+}
+
+// Message mengembalikan pesan setelah "=>", atau nama rule jika tidak ada
+func (r *ExpressionRule) Message() string {
+	return r.message
+}
+
+// Evaluate mengembalikan true jika rule terpicu untuk record saat ini
+func (r *ExpressionRule) Evaluate(prev, current SequentialProvider, window []SequentialProvider) (bool, error) {
+	if r.root == nil {
+		if err := r.compile(); err != nil {
+			return false, err
+		}
+	}
+
+	ctx := &exprContext{prev: prev, current: current, window: window}
+	value, err := r.root.eval(ctx)
+	if err != nil {
+		return false, fmt.Errorf("rule %s: %v", r.Name, err)
+	}
+	return value != 0, nil
+}
+
+// Konteks evaluasi: record sebelumnya, record saat ini dan seluruh window
+type exprContext struct {
+	prev    SequentialProvider
+	current SequentialProvider
+	window  []SequentialProvider
+}
+
+func (ctx *exprContext) value(data SequentialProvider, name string) (float64, error) {
+	if data == nil {
+		return 0, fmt.Errorf("no record for feature %s", name)
+	}
+	value, exists := FeatureValueByName(data, name)
+	if !exists {
+		return 0, fmt.Errorf("feature not found in data: %s", name)
+	}
+	return value, nil
+}
+
+func (ctx *exprContext) windowStats(name string) (WindowStats, error) {
+	values := make([]float64, 0, len(ctx.window))
+	for _, data := range ctx.window {
+		value, err := ctx.value(data, name)
+		if err != nil {
+			return WindowStats{}, err
+		}
+		values = append(values, value)
+	}
+	return CalculateWindowStats(values), nil
+}
+
+func (ctx *exprContext) elapsed() float64 {
+	if ctx.prev == nil {
+		return 1
+	}
+	return ElapsedSeconds(ctx.prev, ctx.current)
+}
+
+// Node AST ekspresi
+type exprNode interface {
+	eval(ctx *exprContext) (float64, error)
+}
+
+type numberNode float64
+
+func (n numberNode) eval(*exprContext) (float64, error) { return float64(n), nil }
+
+type featureNode string
+
+func (n featureNode) eval(ctx *exprContext) (float64, error) {
+	return ctx.value(ctx.current, string(n))
+}
+
+type unaryNode struct {
+	op      string
+	operand exprNode
+}
+
+func (n unaryNode) eval(ctx *exprContext) (float64, error) {
+	value, err := n.operand.eval(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if n.op == "!" {
+		return boolValue(value == 0), nil
+	}
+	return -value, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n binaryNode) eval(ctx *exprContext) (float64, error) {
+	left, err := n.left.eval(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Short-circuit untuk operator logika
+	switch n.op {
+	case "&&":
+		if left == 0 {
+			return 0, nil
+		}
+	case "||":
+		if left != 0 {
+			return 1, nil
+		}
+	}
+
+	right, err := n.right.eval(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case "&&", "||":
+		return boolValue(right != 0), nil
+	case "==":
+		return boolValue(left == right), nil
+	case "!=":
+		return boolValue(left != right), nil
+	case "<":
+		return boolValue(left < right), nil
+	case "<=":
+		return boolValue(left <= right), nil
+	case ">":
+		return boolValue(left > right), nil
+	case ">=":
+		return boolValue(left >= right), nil
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	case "/":
+		if right == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return left / right, nil
+	}
+
+	return 0, fmt.Errorf("unknown operator %s", n.op)
+}
+
+// Fungsi dengan argumen ekspresi biasa
+type mathCallNode struct {
+	name string
+	args []exprNode
+}
+
+func (n mathCallNode) eval(ctx *exprContext) (float64, error) {
+	values := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		value, err := arg.eval(ctx)
+		if err != nil {
+			return 0, err
+		}
+		values[i] = value
+	}
+
+	switch n.name {
+	case "abs":
+		return math.Abs(values[0]), nil
+	case "min":
+		return math.Min(values[0], values[1]), nil
+	case "max":
+		return math.Max(values[0], values[1]), nil
+	case "elapsed":
+		return ctx.elapsed(), nil
+	}
+
+	return 0, fmt.Errorf("unknown function %s", n.name)
+}
+
+// Fungsi dengan argumen nama feature
+type featureCallNode struct {
+	name    string
+	feature string
+}
+
+func (n featureCallNode) eval(ctx *exprContext) (float64, error) {
+	switch n.name {
+	case "prev":
+		return ctx.value(ctx.prev, n.feature)
+	case "delta", "rate":
+		current, err := ctx.value(ctx.current, n.feature)
+		if err != nil {
+			return 0, err
+		}
+		prev, err := ctx.value(ctx.prev, n.feature)
+		if err != nil {
+			return 0, err
+		}
+		if n.name == "rate" {
+			return (current - prev) / ctx.elapsed(), nil
+		}
+		return current - prev, nil
+	}
+
+	stats, err := ctx.windowStats(n.feature)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.name {
+	case "mean":
+		return stats.Mean, nil
+	case "stddev":
+		return stats.StdDev, nil
+	case "wmin":
+		return stats.Min, nil
+	case "wmax":
+		return stats.Max, nil
+	case "slope":
+		return stats.Slope, nil
+	case "zscore":
+		return stats.ZScore, nil
+	}
+
+	return 0, fmt.Errorf("unknown function %s", n.name)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Jumlah argumen fungsi matematika
+var mathFunctions = map[string]int{
+	"abs":     1,
+	"min":     2,
+	"max":     2,
+	"elapsed": 0,
+}
+
+// Fungsi yang argumennya harus nama feature
+var featureFunctions = map[string]bool{
+	"prev":   true,
+	"delta":  true,
+	"rate":   true,
+	"mean":   true,
+	"stddev": true,
+	"wmin":   true,
+	"wmax":   true,
+	"slope":  true,
+	"zscore": true,
+}
+
+type exprTokenKind int
+
+const (
+	tokenEOF exprTokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenString
+	tokenOperator
+)
+
+type exprToken struct {
+	kind  exprTokenKind
+	text  string
+	value float64
+	pos   int
+}
+
+// Pecah ekspresi menjadi token
+func tokenizeExpression(input string) ([]exprToken, error) {
+	var tokens []exprToken
+	operators := []string{"=>", "&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "(", ")", ","}
+
+	i := 0
+	for i < len(input) {
+		c := rune(input[i])
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(input) && (unicode.IsDigit(rune(input[i])) || input[i] == '.') {
+				i++
+			}
+			value, err := strconv.ParseFloat(input[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at %d", input[start:i], start)
+			}
+			tokens = append(tokens, exprToken{kind: tokenNumber, text: input[start:i], value: value, pos: start})
+
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(input) && (unicode.IsLetter(rune(input[i])) || unicode.IsDigit(rune(input[i])) || input[i] == '_' || input[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, text: input[start:i], pos: start})
+
+		case c == '"':
+			start := i
+			i++
+			var sb strings.Builder
+			for i < len(input) && input[i] != '"' {
+				if input[i] == '\\' && i+1 < len(input) {
+					i++
+				}
+				sb.WriteByte(input[i])
+				i++
+			}
+			if i >= len(input) {
+				return nil, fmt.Errorf("unterminated string at %d", start)
+			}
+			i++
+			tokens = append(tokens, exprToken{kind: tokenString, text: sb.String(), pos: start})
+
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(input[i:], op) {
+					tokens = append(tokens, exprToken{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+			}
+		}
+	}
+
+	tokens = append(tokens, exprToken{kind: tokenEOF, pos: len(input)})
+	return tokens, nil
+}
+
+// Parser recursive descent untuk ekspresi rule
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func newExprParser(input string) (*exprParser, error) {
+	tokens, err := tokenizeExpression(input)
+	if err != nil {
+		return nil, err
+	}
+	return &exprParser{tokens: tokens}, nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	token := p.tokens[p.pos]
+	if token.kind != tokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *exprParser) acceptOperator(ops ...string) (string, bool) {
+	token := p.peek()
+	if token.kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if token.text == op {
+			p.next()
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) expectOperator(op string) error {
+	if _, ok := p.acceptOperator(op); !ok {
+		token := p.peek()
+		return fmt.Errorf("expected %q at %d, got %q", op, token.pos, token.text)
+	}
+	return nil
+}
+
+// rule := expr ["=>" string]
+func (p *exprParser) parseRule() (exprNode, string, error) {
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var message string
+	if _, ok := p.acceptOperator("=>"); ok {
+		token := p.next()
+		if token.kind != tokenString {
+			return nil, "", fmt.Errorf("expected message string after => at %d", token.pos)
+		}
+		message = token.text
+	}
+
+	if token := p.peek(); token.kind != tokenEOF {
+		return nil, "", fmt.Errorf("unexpected %q at %d", token.text, token.pos)
+	}
+
+	return root, message, nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOperator("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "||", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOperator("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.acceptOperator("==", "!=", "<=", ">=", "<", ">"); ok {
+		right, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseSum() (exprNode, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOperator("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseTerm() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOperator("*", "/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if op, ok := p.acceptOperator("!", "-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{op: op, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	token := p.next()
+
+	switch token.kind {
+	case tokenNumber:
+		return numberNode(token.value), nil
+
+	case tokenIdent:
+		switch token.text {
+		case "true":
+			return numberNode(1), nil
+		case "false":
+			return numberNode(0), nil
+		}
+
+		if _, ok := p.acceptOperator("("); ok {
+			return p.parseCall(token)
+		}
+		return featureNode(token.text), nil
+
+	case tokenOperator:
+		if token.text == "(" {
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOperator(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+	}
+
+	if token.kind == tokenEOF {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", token.text, token.pos)
+}
+
+// Parsing argumen fungsi, "(" sudah dibaca
+func (p *exprParser) parseCall(name exprToken) (exprNode, error) {
+	if featureFunctions[name.text] {
+		arg := p.next()
+		if arg.kind != tokenIdent {
+			return nil, fmt.Errorf("%s expects a feature name at %d", name.text, arg.pos)
+		}
+		if err := p.expectOperator(")"); err != nil {
+			return nil, err
+		}
+		return featureCallNode{name: name.text, feature: arg.text}, nil
+	}
+
+	arity, exists := mathFunctions[name.text]
+	if !exists {
+		return nil, fmt.Errorf("unknown function %s at %d", name.text, name.pos)
+	}
+
+	var args []exprNode
+	if _, ok := p.acceptOperator(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+
+			if _, ok := p.acceptOperator(","); ok {
+				continue
+			}
+			if err := p.expectOperator(")"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	if len(args) != arity {
+		return nil, fmt.Errorf("%s expects %d arguments, got %d", name.text, arity, len(args))
+	}
+
+	return mathCallNode{name: name.text, args: args}, nil
+}
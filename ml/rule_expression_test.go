@@ -0,0 +1,326 @@
+package ml
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testRecord(timestamp float64, rpm, gear, speed int) Record {
+	return Record{
+		Timestamp: timestamp,
+		Names:     []string{"rpm", "gear", "speed"},
+		Values:    []int{rpm, gear, speed},
+	}
+}
+
+func TestExpressionRuleEvaluate(t *testing.T) {
+	window := []SequentialProvider{
+		testRecord(0, 2000, 2, 30),
+		testRecord(1, 1800, 2, 32),
+		testRecord(3, 1400, 3, 44),
+	}
+	prev, current := window[1], window[2]
+
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{`gear > 1 && rpm < 1500`, true},
+		{`gear > 3 || rpm >= 1500`, false},
+		{`!(gear == 3)`, false},
+		{`abs(delta(speed)) > 5`, true},
+		{`delta(rpm) == -400`, true},
+		{`rate(speed) == 6`, true},
+		{`elapsed() == 2`, true},
+		{`prev(gear) == 2`, true},
+		{`mean(speed) == 35.5`, false},
+		{`wmin(rpm) == 1400 && wmax(rpm) == 2000`, true},
+		{`slope(speed) > 0`, true},
+		{`min(rpm, 1000) + max(gear, 4) * 2 == 1008`, true},
+		{`-speed < 0`, true},
+		{`rpm / gear > 400`, true},
+		{`true`, true},
+		{`false`, false},
+	}
+
+	for _, test := range tests {
+		rule, err := CompileExpressionRule("test", test.expression, SeverityLow)
+		if err != nil {
+			t.Fatalf("%s: %v", test.expression, err)
+		}
+
+		fired, err := rule.Evaluate(prev, current, window)
+		if err != nil {
+			t.Fatalf("%s: %v", test.expression, err)
+		}
+		if fired != test.want {
+			t.Errorf("%s: got %v, want %v", test.expression, fired, test.want)
+		}
+	}
+}
+
+func TestExpressionRuleMessage(t *testing.T) {
+	rule, err := CompileExpressionRule("downshift", `gear > 1 && rpm < 1500 => "should downshift"`, SeverityMedium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Message() != "should downshift" {
+		t.Errorf("message: got %q", rule.Message())
+	}
+
+	rule, err = CompileExpressionRule("jump", `abs(delta(speed)) > 5`, SeverityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Message() != "jump" {
+		t.Errorf("default message: got %q", rule.Message())
+	}
+}
+
+func TestExpressionRuleParseErrors(t *testing.T) {
+	invalid := []string{
+		``,
+		`rpm >`,
+		`(rpm > 1`,
+		`rpm > 1 =>`,
+		`rpm > 1 => 5`,
+		`rpm > 1 => "unterminated`,
+		`unknown(rpm)`,
+		`delta(rpm + 1)`,
+		`abs(rpm, gear)`,
+		`rpm # 1`,
+		`rpm 1`,
+	}
+
+	for _, expression := range invalid {
+		if _, err := CompileExpressionRule("bad", expression, SeverityLow); err == nil {
+			t.Errorf("%q: expected parse error", expression)
+		}
+	}
+}
+
+func TestExpressionRuleEvaluateErrors(t *testing.T) {
+	current := testRecord(1, 1000, 1, 10)
+
+	rule, err := CompileExpressionRule("missing", `brake > 0`, SeverityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rule.Evaluate(nil, current, nil); err == nil {
+		t.Error("expected error for unknown feature")
+	}
+
+	rule, err = CompileExpressionRule("noprev", `delta(rpm) > 0`, SeverityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rule.Evaluate(nil, current, nil); err == nil {
+		t.Error("expected error without previous record")
+	}
+
+	rule, err = CompileExpressionRule("divzero", `rpm / (gear - 1) > 0`, SeverityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rule.Evaluate(nil, current, nil); err == nil {
+		t.Error("expected division by zero error")
+	}
+}
+
+func TestExpressionComparatorInWindowDetector(t *testing.T) {
+	comparator := NewExpressionComparator()
+	if err := comparator.AddRule("downshift", `gear > 1 && rpm < 1500 => "should downshift"`, SeverityMedium); err != nil {
+		t.Fatal(err)
+	}
+	if err := comparator.AddRule("speed_jump", `abs(delta(speed)) > 5`, SeverityHigh); err != nil {
+		t.Fatal(err)
+	}
+	if err := comparator.AddRule("downshift", `rpm < 1000`, SeverityLow); err == nil {
+		t.Error("expected duplicate rule error")
+	}
+
+	wd := NewWindowDetector(3)
+	if err := wd.AddFeatureConfig(FeatureConfig{Name: "rules", Threshold: 0.5, RecordComparator: comparator}); err != nil {
+		t.Fatal(err)
+	}
+
+	records := []Record{
+		testRecord(0, 2000, 2, 30),
+		testRecord(1, 2100, 2, 31),
+		testRecord(2, 1400, 2, 32),
+	}
+
+	var report *AnomalyReport
+	for _, record := range records {
+		var err error
+		report, err = wd.AddDataWithReport(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !report.IsAnomaly {
+		t.Fatalf("expected anomaly, got %s", report)
+	}
+	if report.Severity != SeverityMedium {
+		t.Errorf("severity: got %s, want medium", report.Severity)
+	}
+	fired := report.Fired()
+	if len(fired) != 1 || fired[0].Check != "downshift" || fired[0].Rule != "should downshift" {
+		t.Errorf("unexpected fired results: %+v", fired)
+	}
+
+	report, err := wd.AddDataWithReport(testRecord(3, 2000, 2, 40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Severity != SeverityHigh || !strings.Contains(report.String(), "speed_jump") {
+		t.Errorf("expected speed_jump with high severity, got %s", report)
+	}
+}
+
+func TestExpressionComparatorConfig(t *testing.T) {
+	data := []byte(`{
+		"windowSize": 2,
+		"features": [{
+			"name": "rules",
+			"threshold": 0.5,
+			"comparator": {
+				"type": "expression",
+				"params": {"Rules": [{"Name": "overrev", "Expression": "rpm > 6000 => \"over redline\"", "Severity": "high"}]}
+			}
+		}]
+	}`)
+
+	var config DetectorConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := config.NewDetector()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Konfigurasi harus bisa ditulis ulang dan dibaca kembali
+	roundTrip, err := wd.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := json.Marshal(roundTrip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded DetectorConfig
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if wd, err = decoded.NewDetector(); err != nil {
+		t.Fatal(err)
+	}
+
+	wd.AddDataWithReport(testRecord(0, 5000, 4, 80))
+	report, err := wd.AddDataWithReport(testRecord(1, 6500, 4, 82))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Severity != SeverityHigh || !strings.Contains(report.String(), "over redline") {
+		t.Errorf("unexpected report: %s", report)
+	}
+
+	bad := []byte(`{"Rules": [{"Name": "bad", "Expression": "rpm >"}]}`)
+	if _, err := NewComparator("expression", bad); err == nil {
+		t.Error("expected compile error from params")
+	}
+}
+
+func TestExpressionComparatorFailingRule(t *testing.T) {
+	comparator := NewExpressionComparator()
+	if err := comparator.AddRule("divzero", `rpm / (gear - 2) > 0`, SeverityHigh); err != nil {
+		t.Fatal(err)
+	}
+	if err := comparator.AddRule("missing", `brake > 0`, SeverityHigh); err != nil {
+		t.Fatal(err)
+	}
+	if err := comparator.AddRule("downshift", `gear > 1 && rpm < 1500 => "should downshift"`, SeverityMedium); err != nil {
+		t.Fatal(err)
+	}
+
+	wd := NewWindowDetector(2)
+	if err := wd.AddFeatureConfig(FeatureConfig{Name: "rules", Threshold: 0.5, RecordComparator: comparator}); err != nil {
+		t.Fatal(err)
+	}
+
+	var report *AnomalyReport
+	for _, record := range []Record{testRecord(0, 2000, 2, 30), testRecord(1, 1400, 2, 31)} {
+		var err error
+		report, err = wd.AddDataWithReport(record)
+		if err != nil {
+			t.Fatalf("failing rule must not abort the report: %v", err)
+		}
+	}
+
+	if !report.IsAnomaly || report.Severity != SeverityMedium {
+		t.Fatalf("expected medium anomaly from downshift, got %s", report)
+	}
+	if len(report.Results) != 3 {
+		t.Fatalf("expected 3 results, got %+v", report.Results)
+	}
+
+	errors := map[string]string{}
+	for _, result := range report.Results {
+		errors[result.Check] = result.Error
+		if result.Error != "" && result.Exceeded {
+			t.Errorf("failing rule %s must not fire", result.Check)
+		}
+	}
+	if !strings.Contains(errors["divzero"], "division by zero") {
+		t.Errorf("divzero: got error %q", errors["divzero"])
+	}
+	if !strings.Contains(errors["missing"], "brake") {
+		t.Errorf("missing: got error %q", errors["missing"])
+	}
+	if errors["downshift"] != "" {
+		t.Errorf("downshift: unexpected error %q", errors["downshift"])
+	}
+}
+
+// Comparator yang gagal dicatat di FeatureResult.Error dan tidak dianggap sebagai anomali
+func TestComparatorErrorsAreNotDetections(t *testing.T) {
+	changePoint := NewChangePointDetector()
+	changePoint.Features = []string{"brake"}
+
+	hmm := NewDrivingHMM()
+	hmm.Features = []string{"rpm", "brake", "rate(speed)"}
+
+	wd := NewWindowDetector(2)
+	for _, config := range []FeatureConfig{
+		{Name: "changepoint", Threshold: 0.5, RecordComparator: changePoint},
+		{Name: "hmm", Threshold: 1, RecordComparator: hmm},
+	} {
+		if err := wd.AddFeatureConfig(config); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var report *AnomalyReport
+	for _, record := range []Record{testRecord(0, 2000, 2, 30), testRecord(1, 2100, 2, 31)} {
+		var err error
+		report, err = wd.AddDataWithReport(record)
+		if err != nil {
+			t.Fatalf("failing comparator must not abort the report: %v", err)
+		}
+	}
+
+	if report.IsAnomaly {
+		t.Errorf("failing comparators must not fire: %s", report)
+	}
+	if len(report.Results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", report.Results)
+	}
+	for _, result := range report.Results {
+		if result.Exceeded || result.Score != 0 || !strings.Contains(result.Error, "brake") {
+			t.Errorf("%s: got %+v", result.Feature, result)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// RuleCondition adalah batas interval untuk satu feature: Lower < value <= Upper
+type RuleCondition struct {
+	Feature  int
+	HasLower bool
+	Lower    int
+	HasUpper bool
+	Upper    int
+}
+
+// Rule adalah satu aturan IF-THEN hasil dari path root ke leaf
+type Rule struct {
+	Conditions []RuleCondition
+	Prediction bool
+	Count      int     // jumlah sampel yang memenuhi kondisi
+	Support    float64 // Count dibagi jumlah seluruh sampel
+	Confidence float64 // proporsi sampel yang label-nya sama dengan Prediction
+}
+
+// RuleSet adalah classifier berbasis rule, rule pertama yang cocok menentukan prediksi
+type RuleSet struct {
+	FeatureNames []string
+	Rules        []Rule
+	Default      bool
+}
+
+// ExtractRules mengubah setiap path root ke leaf menjadi rule yang sudah disederhanakan.
+// dataset dipakai untuk menghitung support dan confidence, boleh nil
+func (node *Node) ExtractRules(dataset DataSet, featureNames []string) *RuleSet {
+	var rules []Rule
+	node.collectRules(map[int]RuleCondition{}, &rules)
+
+	rules = mergeRules(rules)
+
+	ruleSet := &RuleSet{
+		FeatureNames: featureNames,
+		Rules:        rules,
+	}
+	ruleSet.Evaluate(dataset)
+
+	return ruleSet
+}
+
+// Telusuri tree dan persempit interval setiap feature sepanjang path
+func (node *Node) collectRules(conditions map[int]RuleCondition, rules *[]Rule) {
+	if node == nil {
+		return
+	}
+
+	if node.IsLeaf {
+		*rules = append(*rules, Rule{
+			Conditions: sortedConditions(conditions),
+			Prediction: node.Prediction,
+		})
+		return
+	}
+
+	// Cabang kiri: feature <= threshold
+	left := copyConditions(conditions)
+	cond := left[node.Feature]
+	cond.Feature = node.Feature
+	if !cond.HasUpper || node.Threshold < cond.Upper {
+		cond.HasUpper = true
+		cond.Upper = node.Threshold
+	}
+	left[node.Feature] = cond
+	node.Left.collectRules(left, rules)
+
+	// Cabang kanan: feature > threshold
+	right := copyConditions(conditions)
+	cond = right[node.Feature]
+	cond.Feature = node.Feature
+	if !cond.HasLower || node.Threshold > cond.Lower {
+		cond.HasLower = true
+		cond.Lower = node.Threshold
+	}
+	right[node.Feature] = cond
+	node.Right.collectRules(right, rules)
+}
+
+// Gabungkan rule dengan prediksi sama yang hanya berbeda pada satu interval yang bersebelahan
+func mergeRules(rules []Rule) []Rule {
+	merged := true
+	for merged {
+		merged = false
+		for i := 0; i < len(rules) && !merged; i++ {
+			for j := i + 1; j < len(rules); j++ {
+				combined, ok := mergeRulePair(rules[i], rules[j])
+				if !ok {
+					continue
+				}
+				rules[i] = combined
+				rules = append(rules[:j], rules[j+1:]...)
+				merged = true
+				break
+			}
+		}
+	}
+	return rules
+}
+
+func mergeRulePair(a, b Rule) (Rule, bool) {
+	if a.Prediction != b.Prediction || len(a.Conditions) != len(b.Conditions) {
+		return Rule{}, false
+	}
+
+	diff := -1
+	for k := range a.Conditions {
+		if a.Conditions[k] == b.Conditions[k] {
+			continue
+		}
+		if diff != -1 || a.Conditions[k].Feature != b.Conditions[k].Feature {
+			return Rule{}, false
+		}
+		diff = k
+	}
+
+	if diff == -1 {
+		// Kondisi identik, cukup ambil salah satu
+		return a, true
+	}
+
+	ca, cb := a.Conditions[diff], b.Conditions[diff]
+	var cond RuleCondition
+	switch {
+	case ca.HasUpper && cb.HasLower && ca.Upper == cb.Lower:
+		cond = RuleCondition{Feature: ca.Feature, HasLower: ca.HasLower, Lower: ca.Lower, HasUpper: cb.HasUpper, Upper: cb.Upper}
+	case cb.HasUpper && ca.HasLower && cb.Upper == ca.Lower:
+		cond = RuleCondition{Feature: ca.Feature, HasLower: cb.HasLower, Lower: cb.Lower, HasUpper: ca.HasUpper, Upper: ca.Upper}
+	default:
+		return Rule{}, false
+	}
+
+	conditions := make([]RuleCondition, 0, len(a.Conditions))
+	for k, c := range a.Conditions {
+		if k == diff {
+			// Interval tanpa batas tidak perlu ditulis lagi
+			if !cond.HasLower && !cond.HasUpper {
+				continue
+			}
+			c = cond
+		}
+		conditions = append(conditions, c)
+	}
+
+	return Rule{Conditions: conditions, Prediction: a.Prediction}, true
+}
+
+func copyConditions(conditions map[int]RuleCondition) map[int]RuleCondition {
+	result := make(map[int]RuleCondition, len(conditions))
+	for k, v := range conditions {
+		result[k] = v
+	}
+	return result
+}
+
+func sortedConditions(conditions map[int]RuleCondition) []RuleCondition {
+	result := make([]RuleCondition, 0, len(conditions))
+	for _, c := range conditions {
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Feature < result[j].Feature
+	})
+	return result
+}
+
+// Match mengecek apakah data memenuhi kondisi
+func (c RuleCondition) Match(data HasValueCount) bool {
+	value := data.GetFeatureValue(c.Feature)
+	if c.HasLower && value <= c.Lower {
+		return false
+	}
+	if c.HasUpper && value > c.Upper {
+		return false
+	}
+	return true
+}
+
+// Match mengecek apakah data memenuhi semua kondisi rule
+func (r Rule) Match(data HasValueCount) bool {
+	for _, c := range r.Conditions {
+		if !c.Match(data) {
+			return false
+		}
+	}
+	return true
+}
+
+// Region leaf bisa kosong jika path memuat kondisi yang saling bertentangan
+func (r Rule) isEmpty() bool {
+	for _, c := range r.Conditions {
+		if c.HasLower && c.HasUpper && c.Lower >= c.Upper {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate menghitung ulang support, confidence dan default prediction dari dataset
+func (rs *RuleSet) Evaluate(dataset DataSet) {
+	for i := range rs.Rules {
+		rule := &rs.Rules[i]
+		rule.Count = 0
+		rule.Support = 0
+		rule.Confidence = 0
+
+		correct := 0
+		for _, data := range dataset {
+			if !rule.Match(data) {
+				continue
+			}
+			rule.Count++
+			if data.IsAnomaly() == rule.Prediction {
+				correct++
+			}
+		}
+
+		if len(dataset) > 0 {
+			rule.Support = float64(rule.Count) / float64(len(dataset))
+		}
+		if rule.Count > 0 {
+			rule.Confidence = float64(correct) / float64(rule.Count)
+		}
+	}
+
+	// Default mengikuti label mayoritas
+	if len(dataset) > 0 {
+		rs.Default = dataset.calculateAttackProportion() >= 0.5
+	}
+}
+
+// Predict mengembalikan prediksi dari rule pertama yang cocok
+func (rs *RuleSet) Predict(data FeatureProvider) bool {
+	for _, rule := range rs.Rules {
+		if rule.Match(data) {
+			return rule.Prediction
+		}
+	}
+	return rs.Default
+}
+
+func (rs *RuleSet) GetPredictionAccuration(testData DataSet) float64 {
+	correct := 0
+	for _, data := range testData {
+		if rs.Predict(data) == data.IsAnomaly() {
+			correct++
+		}
+	}
+
+	accuracy := float64(correct) / float64(len(testData))
+	return accuracy * 100
+}
+
+func (rs *RuleSet) featureName(feature int) string {
+	if feature >= 0 && feature < len(rs.FeatureNames) {
+		return rs.FeatureNames[feature]
+	}
+	return fmt.Sprintf("feature%d", feature)
+}
+
+func (rs *RuleSet) conditionString(c RuleCondition) string {
+	name := rs.featureName(c.Feature)
+	switch {
+	case c.HasLower && c.HasUpper:
+		return fmt.Sprintf("%d < %s <= %d", c.Lower, name, c.Upper)
+	case c.HasLower:
+		return fmt.Sprintf("%s > %d", name, c.Lower)
+	case c.HasUpper:
+		return fmt.Sprintf("%s <= %d", name, c.Upper)
+	default:
+		return "true"
+	}
+}
+
+// RuleString menampilkan satu rule dalam bentuk IF-THEN
+func (rs *RuleSet) RuleString(rule Rule) string {
+	parts := make([]string, 0, len(rule.Conditions))
+	for _, c := range rule.Conditions {
+		parts = append(parts, rs.conditionString(c))
+	}
+
+	condition := "true"
+	if len(parts) > 0 {
+		condition = strings.Join(parts, " AND ")
+	}
+
+	return fmt.Sprintf("IF %s THEN %s (support=%.2f%%, count=%d, confidence=%.2f%%)",
+		condition,
+		map[bool]string{true: "Attack", false: "Normal"}[rule.Prediction],
+		rule.Support*100,
+		rule.Count,
+		rule.Confidence*100)
+}
+
+func (rs *RuleSet) String() string {
+	var sb strings.Builder
+	for i, rule := range rs.Rules {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, rs.RuleString(rule))
+	}
+	fmt.Fprintf(&sb, "DEFAULT %s\n", map[bool]string{true: "Attack", false: "Normal"}[rs.Default])
+	return sb.String()
+}
+
+func (rs *RuleSet) PrintRules() {
+	fmt.Print(rs.String())
+}
+
+// Fungsi untuk save rule set ke file
+func (rs *RuleSet) SaveRules(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rs)
+}
+
+// Fungsi untuk load rule set dari file
+func LoadRules(filename string) (*RuleSet, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rs RuleSet
+	decoder := json.NewDecoder(file)
+	if err := decoder.Decode(&rs); err != nil {
+		return nil, err
+	}
+
+	return &rs, nil
+}
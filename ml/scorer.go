@@ -0,0 +1,167 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// AnomalyScorer adalah interface bersama untuk tree dan model anomali lain.
+// Score makin besar makin anomali, Predict memakai threshold milik model
+type AnomalyScorer interface {
+	Score(data HasValueCount) float64
+	Predict(data FeatureProvider) bool
+}
+
+// Score untuk tree adalah 1 jika diprediksi anomali dan 0 jika normal
+func (node *Node) Score(data HasValueCount) float64 {
+	current := node
+	for !current.IsLeaf {
+		if data.GetFeatureValue(current.Feature) <= current.Threshold {
+			current = current.Left
+		} else {
+			current = current.Right
+		}
+	}
+	if current.Prediction {
+		return 1
+	}
+	return 0
+}
+
+// EvaluationReport adalah hasil evaluasi model terhadap data berlabel
+type EvaluationReport struct {
+	Total          int
+	TruePositive   int
+	FalsePositive  int
+	TrueNegative   int
+	FalseNegative  int
+	AUC            float64 // area di bawah kurva ROC dari Score, NaN jika hanya ada satu kelas
+	HasBothClasses bool
+}
+
+func (r *EvaluationReport) Accuracy() float64 {
+	if r.Total == 0 {
+		return 0
+	}
+	return float64(r.TruePositive+r.TrueNegative) / float64(r.Total) * 100
+}
+
+func (r *EvaluationReport) Precision() float64 {
+	if r.TruePositive+r.FalsePositive == 0 {
+		return 0
+	}
+	return float64(r.TruePositive) / float64(r.TruePositive+r.FalsePositive) * 100
+}
+
+func (r *EvaluationReport) Recall() float64 {
+	if r.TruePositive+r.FalseNegative == 0 {
+		return 0
+	}
+	return float64(r.TruePositive) / float64(r.TruePositive+r.FalseNegative) * 100
+}
+
+func (r *EvaluationReport) FalsePositiveRate() float64 {
+	if r.FalsePositive+r.TrueNegative == 0 {
+		return 0
+	}
+	return float64(r.FalsePositive) / float64(r.FalsePositive+r.TrueNegative) * 100
+}
+
+func (r *EvaluationReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Samples: %d\n", r.Total)
+	fmt.Fprintf(&sb, "TP=%d FP=%d TN=%d FN=%d\n", r.TruePositive, r.FalsePositive, r.TrueNegative, r.FalseNegative)
+	fmt.Fprintf(&sb, "Accuracy: %.2f%%\n", r.Accuracy())
+	fmt.Fprintf(&sb, "Precision: %.2f%%\n", r.Precision())
+	fmt.Fprintf(&sb, "Recall: %.2f%%\n", r.Recall())
+	fmt.Fprintf(&sb, "False positive rate: %.2f%%\n", r.FalsePositiveRate())
+	if r.HasBothClasses {
+		fmt.Fprintf(&sb, "ROC AUC: %.4f\n", r.AUC)
+	} else {
+		fmt.Fprintf(&sb, "ROC AUC: n/a (data has only one label)\n")
+	}
+	return sb.String()
+}
+
+func (r *EvaluationReport) PrintReport() {
+	fmt.Print(r.String())
+}
+
+// Evaluate membandingkan prediksi dan skor model dengan label IsAnomaly
+func Evaluate(scorer AnomalyScorer, dataset DataSet) *EvaluationReport {
+	report := &EvaluationReport{Total: len(dataset), AUC: math.NaN()}
+
+	scores := make([]float64, len(dataset))
+	labels := make([]bool, len(dataset))
+	for i, data := range dataset {
+		predicted, actual := scorer.Predict(data), data.IsAnomaly()
+		switch {
+		case predicted && actual:
+			report.TruePositive++
+		case predicted && !actual:
+			report.FalsePositive++
+		case !predicted && actual:
+			report.FalseNegative++
+		default:
+			report.TrueNegative++
+		}
+		scores[i], labels[i] = scorer.Score(data), actual
+	}
+
+	positives := report.TruePositive + report.FalseNegative
+	negatives := report.FalsePositive + report.TrueNegative
+	report.HasBothClasses = positives > 0 && negatives > 0
+	if report.HasBothClasses {
+		report.AUC = rocAUC(scores, labels, positives, negatives)
+	}
+	return report
+}
+
+// AUC dengan rumus Mann-Whitney, skor yang sama mendapat rank rata-rata
+func rocAUC(scores []float64, labels []bool, positives, negatives int) float64 {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return scores[order[a]] < scores[order[b]] })
+
+	var rankSum float64
+	for start := 0; start < len(order); {
+		end := start
+		for end+1 < len(order) && scores[order[end+1]] == scores[order[start]] {
+			end++
+		}
+		rank := float64(start+end)/2 + 1
+		for i := start; i <= end; i++ {
+			if labels[order[i]] {
+				rankSum += rank
+			}
+		}
+		start = end + 1
+	}
+
+	p, n := float64(positives), float64(negatives)
+	return (rankSum - p*(p+1)/2) / (p * n)
+}
+
+// ScoreThreshold mengembalikan skor yang dilewati sekitar rate bagian dari data,
+// dipakai untuk memilih threshold dari perkiraan proporsi anomali atau target false positive
+func ScoreThreshold(scorer AnomalyScorer, dataset DataSet, rate float64) (float64, error) {
+	if len(dataset) == 0 {
+		return 0, fmt.Errorf("dataset is empty")
+	}
+	if rate <= 0 || rate >= 1 {
+		return 0, fmt.Errorf("rate must be between 0 and 1, got %f", rate)
+	}
+
+	scores := make([]float64, len(dataset))
+	for i, data := range dataset {
+		scores[i] = scorer.Score(data)
+	}
+	sort.Float64s(scores)
+
+	index := int(math.Ceil(float64(len(scores))*(1-rate))) - 1
+	return scores[min(max(index, 0), len(scores)-1)], nil
+}
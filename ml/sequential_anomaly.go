@@ -3,11 +3,14 @@ package ml
 import (
 	"fmt"
 	"math"
+	"sort"
 )
 
 type SequentialProvider interface {
 	HasValueCount
 	GetFeatureName(feature int) string
+	// GetTimestamp mengembalikan waktu sampel dalam detik
+	GetTimestamp() float64
 }
 
 // FeatureComparator mendefinisikan bagaimana membandingkan nilai feature
@@ -30,16 +33,164 @@ func (c DefaultComparator) Compare(prev, current float64) float64 {
 	return math.Abs((current - prev) / prev)
 }
 
+// EWMAComparator adalah control chart EWMA untuk mendeteksi drift perlahan pada satu feature.
+// Skornya adalah jarak EWMA dari Mean dalam satuan standar deviasi EWMA,
+// sehingga threshold FeatureConfig biasanya sekitar 3.
+// Comparator ini menyimpan state, buat instance baru untuk setiap stream
+type EWMAComparator struct {
+	Feature string
+	Mean    float64 // dipelajari dari data kalibrasi
+	StdDev  float64 // dipelajari dari data kalibrasi
+	Lambda  float64 // faktor smoothing 0 < Lambda <= 1
+
+	// State, ikut tersimpan di snapshot
+	Value float64
+	Count int
+}
+
+// NewEWMAComparator membuat EWMAComparator dengan lambda default 0.2
+func NewEWMAComparator(feature string, mean, stdDev float64) *EWMAComparator {
+	return &EWMAComparator{Feature: feature, Mean: mean, StdDev: stdDev, Lambda: 0.2}
+}
+
+// CalibrateEWMA mempelajari Mean dan StdDev dari urutan data mengemudi normal
+func CalibrateEWMA(feature string, sequence []SequentialProvider) (*EWMAComparator, error) {
+	mean, stdDev, err := calibrateFeature(feature, sequence)
+	if err != nil {
+		return nil, err
+	}
+	return NewEWMAComparator(feature, mean, stdDev), nil
+}
+
+func (c *EWMAComparator) CompareRecords(prev, current SequentialProvider, window []SequentialProvider) (float64, string) {
+	value, exists := FeatureValueByName(current, c.Feature)
+	if !exists {
+		return 0, ""
+	}
+
+	lambda := c.Lambda
+	if lambda <= 0 || lambda > 1 {
+		lambda = 0.2
+	}
+
+	if c.Count == 0 {
+		c.Value = c.Mean
+	}
+	c.Value = lambda*value + (1-lambda)*c.Value
+	c.Count++
+
+	// Standar deviasi EWMA setelah Count sampel
+	variance := lambda / (2 - lambda) * (1 - math.Pow(1-lambda, 2*float64(c.Count)))
+	sigma := calibratedStdDev(c.StdDev) * math.Sqrt(variance)
+
+	score := math.Abs(c.Value-c.Mean) / sigma
+	return score, fmt.Sprintf("%s EWMA %.2f drifted from mean %.2f (%.2f sigma)", c.Feature, c.Value, c.Mean, score)
+}
+
+// Reset menghapus state EWMA
+func (c *EWMAComparator) Reset() {
+	c.Value = 0
+	c.Count = 0
+}
+
+// CUSUMComparator adalah CUSUM dua sisi untuk mendeteksi pergeseran mean kecil yang bertahan lama.
+// Skornya adalah jumlah kumulatif terbesar (naik atau turun) dalam satuan standar deviasi,
+// sehingga threshold FeatureConfig biasanya 4 sampai 5.
+// Comparator ini menyimpan state, buat instance baru untuk setiap stream
+type CUSUMComparator struct {
+	Feature string
+	Mean    float64 // dipelajari dari data kalibrasi
+	StdDev  float64 // dipelajari dari data kalibrasi
+	Slack   float64 // pergeseran yang diabaikan dalam satuan standar deviasi (k)
+
+	// State, ikut tersimpan di snapshot
+	High float64
+	Low  float64
+}
+
+// NewCUSUMComparator membuat CUSUMComparator dengan slack default 0.5
+func NewCUSUMComparator(feature string, mean, stdDev float64) *CUSUMComparator {
+	return &CUSUMComparator{Feature: feature, Mean: mean, StdDev: stdDev, Slack: 0.5}
+}
+
+// CalibrateCUSUM mempelajari Mean dan StdDev dari urutan data mengemudi normal
+func CalibrateCUSUM(feature string, sequence []SequentialProvider) (*CUSUMComparator, error) {
+	mean, stdDev, err := calibrateFeature(feature, sequence)
+	if err != nil {
+		return nil, err
+	}
+	return NewCUSUMComparator(feature, mean, stdDev), nil
+}
+
+func (c *CUSUMComparator) CompareRecords(prev, current SequentialProvider, window []SequentialProvider) (float64, string) {
+	value, exists := FeatureValueByName(current, c.Feature)
+	if !exists {
+		return 0, ""
+	}
+
+	z := (value - c.Mean) / calibratedStdDev(c.StdDev)
+	c.High = math.Max(0, c.High+z-c.Slack)
+	c.Low = math.Max(0, c.Low-z-c.Slack)
+
+	if c.High >= c.Low {
+		return c.High, fmt.Sprintf("%s CUSUM %.2f shows upward drift from mean %.2f", c.Feature, c.High, c.Mean)
+	}
+	return c.Low, fmt.Sprintf("%s CUSUM %.2f shows downward drift from mean %.2f", c.Feature, c.Low, c.Mean)
+}
+
+// Reset menghapus state CUSUM
+func (c *CUSUMComparator) Reset() {
+	c.High = 0
+	c.Low = 0
+}
+
+// Hitung mean dan standar deviasi satu feature dari data kalibrasi
+func calibrateFeature(feature string, sequence []SequentialProvider) (float64, float64, error) {
+	values := make([]float64, 0, len(sequence))
+	for _, data := range sequence {
+		value, exists := FeatureValueByName(data, feature)
+		if !exists {
+			return 0, 0, fmt.Errorf("feature not found in data: %s", feature)
+		}
+		values = append(values, value)
+	}
+
+	if len(values) < 2 {
+		return 0, 0, fmt.Errorf("calibration needs at least 2 samples, got %d", len(values))
+	}
+
+	mean, stdDev := meanStdDev(values)
+	if stdDev == 0 {
+		return 0, 0, fmt.Errorf("feature %s is constant in calibration data", feature)
+	}
+	return mean, stdDev, nil
+}
+
+// Standar deviasi 0 atau negatif (belum dikalibrasi) dianggap 1
+func calibratedStdDev(stdDev float64) float64 {
+	if stdDev <= 0 {
+		return 1
+	}
+	return stdDev
+}
+
 // FeatureConfig mendefinisikan konfigurasi untuk setiap feature
 type FeatureConfig struct {
 	Name       string
 	Threshold  float64
 	Comparator FeatureComparator
+
+	// RecordComparator melihat record lengkap, dipakai menggantikan Comparator jika diisi
+	RecordComparator RecordComparator
+
+	// WindowChecks dijalankan terhadap seluruh isi window
+	WindowChecks []WindowCheck
 }
 
 // SequentialDetector adalah interface umum untuk deteksi anomali sequential
 type SequentialDetector interface {
 	AddData(data SequentialProvider) (bool, error)
+	AddDataWithReport(data SequentialProvider) (*AnomalyReport, error)
 	SetThreshold(featureName string, threshold float64) error
 	AddFeatureConfig(config FeatureConfig) error
 }
@@ -49,6 +200,10 @@ type WindowDetector struct {
 	WindowSize     int
 	History        []SequentialProvider
 	FeatureConfigs map[string]FeatureConfig
+
+	// CheckTimestamps menandai sampel duplikat, tidak urut dan gap lebih dari MaxGap detik
+	CheckTimestamps bool
+	MaxGap          float64
 }
 
 // NewWindowDetector membuat instance baru WindowDetector
@@ -62,13 +217,28 @@ func NewWindowDetector(windowSize int) *WindowDetector {
 
 // AddFeatureConfig menambahkan konfigurasi untuk feature baru
 func (wd *WindowDetector) AddFeatureConfig(config FeatureConfig) error {
-	if config.Comparator == nil {
+	// Feature yang memakai record comparator atau window check tidak perlu comparator default
+	if config.Comparator == nil && config.RecordComparator == nil && len(config.WindowChecks) == 0 {
 		config.Comparator = DefaultComparator{}
 	}
+
+	// Tree window tidak bisa menilai window yang lebih pendek dari window latihnya
+	if tree, ok := config.RecordComparator.(*WindowTreeComparator); ok && tree.Transformer != nil && tree.Transformer.WindowSize > wd.WindowSize {
+		return fmt.Errorf("feature %s: window tree needs a window of %d samples, detector has %d",
+			config.Name, tree.Transformer.WindowSize, wd.WindowSize)
+	}
+
 	wd.FeatureConfigs[config.Name] = config
 	return nil
 }
 
+// SetTimestampCheck mengaktifkan pengecekan timestamp.
+// maxGap 0 berarti gap tidak dicek
+func (wd *WindowDetector) SetTimestampCheck(maxGap float64) {
+	wd.CheckTimestamps = true
+	wd.MaxGap = maxGap
+}
+
 // SetThreshold mengubah threshold untuk feature tertentu
 func (wd *WindowDetector) SetThreshold(featureName string, threshold float64) error {
 	config, exists := wd.FeatureConfigs[featureName]
@@ -82,12 +252,40 @@ func (wd *WindowDetector) SetThreshold(featureName string, threshold float64) er
 
 // AddData menambahkan data baru dan mendeteksi anomali
 func (wd *WindowDetector) AddData(data SequentialProvider) (bool, error) {
+	report, err := wd.AddDataWithReport(data)
+	if err != nil {
+		return false, err
+	}
+	return report.IsAnomaly, nil
+}
+
+// AddDataWithReport menambahkan data baru dan mengembalikan hasil pengecekan setiap feature
+func (wd *WindowDetector) AddDataWithReport(data SequentialProvider) (*AnomalyReport, error) {
+	timeResult, accepted := wd.checkTimestamp(data)
+
+	// Sampel duplikat atau tidak urut tidak masuk history agar window tetap valid
+	if !accepted {
+		report := &AnomalyReport{
+			Ready:   len(wd.History) >= wd.WindowSize,
+			Results: []FeatureResult{*timeResult},
+		}
+		report.Severity = report.calculateSeverity()
+		report.IsAnomaly = true
+		return report, nil
+	}
+
 	// Tambahkan ke history
 	wd.History = append(wd.History, data)
 
-	// Jika belum cukup history, return false
+	// Jika belum cukup history, return report kosong
 	if len(wd.History) < wd.WindowSize {
-		return false, nil
+		report := &AnomalyReport{}
+		if timeResult != nil {
+			report.Results = append(report.Results, *timeResult)
+			report.Severity = report.calculateSeverity()
+			report.IsAnomaly = true
+		}
+		return report, nil
 	}
 
 	// Jaga ukuran window
@@ -95,41 +293,203 @@ func (wd *WindowDetector) AddData(data SequentialProvider) (bool, error) {
 		wd.History = wd.History[1:]
 	}
 
-	return wd.detectAnomaly()
+	report, err := wd.detectAnomaly()
+	if err != nil {
+		return nil, err
+	}
+
+	if timeResult != nil {
+		report.Results = append([]FeatureResult{*timeResult}, report.Results...)
+		report.Severity = report.calculateSeverity()
+		report.IsAnomaly = true
+	}
+
+	return report, nil
+}
+
+// Periksa timestamp data baru terhadap sampel terakhir di history.
+// Mengembalikan false jika data tidak boleh masuk history
+func (wd *WindowDetector) checkTimestamp(data SequentialProvider) (*FeatureResult, bool) {
+	if !wd.CheckTimestamps || len(wd.History) == 0 {
+		return nil, true
+	}
+
+	prev := wd.History[len(wd.History)-1].GetTimestamp()
+	current := data.GetTimestamp()
+	elapsed := current - prev
+
+	result := &FeatureResult{
+		Feature:   "timestamp",
+		Score:     elapsed,
+		Threshold: wd.MaxGap,
+		Previous:  prev,
+		Current:   current,
+		Exceeded:  true,
+	}
+
+	switch {
+	case elapsed == 0:
+		result.Check = "duplicate"
+		result.Rule = fmt.Sprintf("duplicate timestamp %.3f", current)
+		return result, false
+	case elapsed < 0:
+		result.Check = "out_of_order"
+		result.Rule = fmt.Sprintf("timestamp %.3f is before previous %.3f", current, prev)
+		return result, false
+	case wd.MaxGap > 0 && elapsed > wd.MaxGap:
+		result.Check = "gap"
+		result.Rule = fmt.Sprintf("gap of %.3fs exceeds %.3fs", elapsed, wd.MaxGap)
+		return result, true
+	}
+
+	return nil, true
 }
 
-func (wd *WindowDetector) detectAnomaly() (bool, error) {
+func (wd *WindowDetector) detectAnomaly() (*AnomalyReport, error) {
 	currentData := wd.History[len(wd.History)-1]
 	prevData := wd.History[len(wd.History)-2]
 
+	report := &AnomalyReport{Ready: true}
+
+	// Urutkan nama feature agar hasil report selalu sama
+	featureNames := make([]string, 0, len(wd.FeatureConfigs))
+	for featureName := range wd.FeatureConfigs {
+		featureNames = append(featureNames, featureName)
+	}
+	sort.Strings(featureNames)
+
 	// Periksa setiap feature yang terdaftar
-	for featureName, config := range wd.FeatureConfigs {
-		featureIndex := -1
+	for _, featureName := range featureNames {
+		config := wd.FeatureConfigs[featureName]
+
 		// Cari index feature
-		for i := 0; i < currentData.GetFeatureCount(); i++ {
-			// Asumsikan nama feature disimpan di metadata atau cara lain
-			if currentData.GetFeatureName(i) == featureName {
-				featureIndex = i
-				break
-			}
+		featureIndex := FeatureIndexByName(currentData, featureName)
+
+		// Record comparator boleh memakai nama yang bukan kolom data (rule lintas feature)
+		if featureIndex == -1 && (config.RecordComparator == nil || len(config.WindowChecks) > 0) {
+			return nil, fmt.Errorf("feature not found in data: %s", featureName)
 		}
 
-		if featureIndex == -1 {
-			return false, fmt.Errorf("feature not found in data: %s", featureName)
+		var prev, current float64
+		if featureIndex != -1 {
+			prev = float64(prevData.GetFeatureValue(featureIndex))
+			current = float64(currentData.GetFeatureValue(featureIndex))
 		}
 
-		// Bandingkan nilai
-		change := config.Comparator.Compare(
-			float64(prevData.GetFeatureValue(featureIndex)),
-			float64(currentData.GetFeatureValue(featureIndex)),
-		)
+		// Comparator lama dijalankan lewat adapter
+		comparator := config.RecordComparator
+		if comparator == nil && config.Comparator != nil {
+			comparator = FeatureComparatorAdapter{Feature: featureName, Comparator: config.Comparator}
+		}
 
-		if change > config.Threshold {
-			return true, nil
+		// Rule bernama dilaporkan satu per satu dengan severity masing-masing
+		if rules, ok := comparator.(RuleComparator); ok {
+			results, err := rules.EvaluateRules(prevData, currentData, wd.History)
+			if err != nil {
+				report.Results = append(report.Results, FeatureResult{
+					Feature:   featureName,
+					Check:     "change",
+					Threshold: config.Threshold,
+					Previous:  prev,
+					Current:   current,
+					Error:     err.Error(),
+				})
+			}
+
+			for _, result := range results {
+				score := boolValue(result.Fired)
+				report.Results = append(report.Results, FeatureResult{
+					Feature:   featureName,
+					Check:     result.Name,
+					Score:     score,
+					Threshold: config.Threshold,
+					Previous:  prev,
+					Current:   current,
+					Exceeded:  result.Fired,
+					Rule:      result.Message,
+					Severity:  result.Severity,
+					Error:     result.Error,
+				})
+			}
+		} else if comparator != nil {
+			// Bandingkan nilai. Comparator yang gagal dicatat dan tidak dianggap terpicu
+			var change float64
+			var rule, errorMessage string
+			if errorComparator, ok := comparator.(ErrorComparator); ok {
+				var err error
+				change, rule, err = errorComparator.CompareRecordsWithError(prevData, currentData, wd.History)
+				if err != nil {
+					change, errorMessage = 0, err.Error()
+				}
+			} else {
+				change, rule = comparator.CompareRecords(prevData, currentData, wd.History)
+			}
+
+			exceeded := change > config.Threshold
+			if exceeded && rule == "" {
+				rule = fmt.Sprintf("%s change %.2f exceeds threshold %.2f", featureName, change, config.Threshold)
+			}
+			if !exceeded {
+				rule = ""
+			}
+
+			report.Results = append(report.Results, FeatureResult{
+				Feature:   featureName,
+				Check:     "change",
+				Score:     change,
+				Threshold: config.Threshold,
+				Previous:  prev,
+				Current:   current,
+				Exceeded:  exceeded,
+				Rule:      rule,
+				Error:     errorMessage,
+			})
+		}
+
+		// Bandingkan dengan statistik seluruh window
+		if len(config.WindowChecks) > 0 {
+			values := wd.windowValues(featureIndex)
+			for _, check := range config.WindowChecks {
+				score := check.Comparator.CompareWindow(values)
+				exceeded := score > check.Threshold
+
+				name := "window"
+				if stat, ok := check.Comparator.(StatComparator); ok {
+					name = stat.Statistic.String()
+				}
+
+				var rule string
+				if exceeded {
+					rule = fmt.Sprintf("%s window %s %.2f exceeds %.2f", featureName, name, score, check.Threshold)
+				}
+
+				report.Results = append(report.Results, FeatureResult{
+					Feature:   featureName,
+					Check:     name,
+					Score:     score,
+					Threshold: check.Threshold,
+					Previous:  prev,
+					Current:   current,
+					Exceeded:  exceeded,
+					Rule:      rule,
+				})
+			}
 		}
 	}
 
-	return false, nil
+	report.Severity = report.calculateSeverity()
+	report.IsAnomaly = report.Severity != SeverityNone
+
+	return report, nil
+}
+
+// Ambil nilai satu feature dari seluruh history, paling lama di depan
+func (wd *WindowDetector) windowValues(featureIndex int) []float64 {
+	values := make([]float64, len(wd.History))
+	for i, data := range wd.History {
+		values[i] = float64(data.GetFeatureValue(featureIndex))
+	}
+	return values
 }
 
 // Contoh penggunaan:
@@ -0,0 +1,169 @@
+package ml
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// TrainingEventType adalah jenis event selama membangun tree
+type TrainingEventType string
+
+const (
+	EventNode  TrainingEventType = "node"  // node baru mulai diproses
+	EventSplit TrainingEventType = "split" // split terbaik sudah dipilih
+	EventLeaf  TrainingEventType = "leaf"  // node menjadi leaf
+)
+
+// Alasan sebuah node menjadi leaf
+const (
+	LeafEmpty    = "empty"
+	LeafMaxDepth = "max_depth"
+	LeafPure     = "pure"
+	LeafNoGain   = "no_gain"
+)
+
+// TrainingEvent adalah satu langkah rekursi BuildTree
+type TrainingEvent struct {
+	Type             TrainingEventType
+	Depth            int
+	Size             int // jumlah data di node
+	AttackProportion float64
+
+	// Diisi untuk EventSplit
+	Feature   int
+	Threshold int
+	Gain      float64
+	LeftSize  int
+	RightSize int
+
+	// Diisi untuk EventLeaf
+	Prediction bool
+	Reason     string
+}
+
+// TrainingObserver menerima event selama training
+type TrainingObserver interface {
+	OnTrainingEvent(event TrainingEvent)
+}
+
+// TrainingObserverFunc adapter agar fungsi biasa bisa dipakai sebagai observer
+type TrainingObserverFunc func(event TrainingEvent)
+
+func (f TrainingObserverFunc) OnTrainingEvent(event TrainingEvent) {
+	f(event)
+}
+
+// NopObserver adalah observer default yang tidak melakukan apa-apa
+type NopObserver struct{}
+
+func (NopObserver) OnTrainingEvent(TrainingEvent) {}
+
+// TrainingTrace mengumpulkan semua event sebagai data terstruktur.
+// Event hanya dibaca lewat Events dan Filter agar aman dipakai selama training berjalan
+type TrainingTrace struct {
+	mu     sync.Mutex
+	events []TrainingEvent
+}
+
+func (t *TrainingTrace) OnTrainingEvent(event TrainingEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, event)
+}
+
+// Events mengembalikan salinan semua event yang sudah terkumpul
+func (t *TrainingTrace) Events() []TrainingEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]TrainingEvent(nil), t.events...)
+}
+
+// Filter mengembalikan event dengan tipe tertentu
+func (t *TrainingTrace) Filter(eventType TrainingEventType) []TrainingEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var result []TrainingEvent
+	for _, event := range t.events {
+		if event.Type == eventType {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// SlogObserver meneruskan event ke log/slog
+type SlogObserver struct {
+	Logger *slog.Logger
+	Level  slog.Level
+}
+
+// NewSlogObserver membuat observer slog dengan level debug
+func NewSlogObserver(logger *slog.Logger) *SlogObserver {
+	return &SlogObserver{
+		Logger: logger,
+		Level:  slog.LevelDebug,
+	}
+}
+
+func (o *SlogObserver) OnTrainingEvent(event TrainingEvent) {
+	attrs := []slog.Attr{
+		slog.Int("depth", event.Depth),
+		slog.Int("size", event.Size),
+		slog.Float64("attack_proportion", event.AttackProportion),
+	}
+
+	switch event.Type {
+	case EventSplit:
+		attrs = append(attrs,
+			slog.Int("feature", event.Feature),
+			slog.Int("threshold", event.Threshold),
+			slog.Float64("gain", event.Gain),
+			slog.Int("left_size", event.LeftSize),
+			slog.Int("right_size", event.RightSize),
+		)
+	case EventLeaf:
+		attrs = append(attrs,
+			slog.Bool("prediction", event.Prediction),
+			slog.String("reason", event.Reason),
+		)
+	}
+
+	o.Logger.LogAttrs(context.Background(), o.Level, "build tree "+string(event.Type), attrs...)
+}
+
+// PrintObserver menampilkan proses training sebagai tree di writer
+type PrintObserver struct {
+	Writer io.Writer
+}
+
+// NewPrintObserver membuat observer yang menulis ke writer
+func NewPrintObserver(w io.Writer) *PrintObserver {
+	return &PrintObserver{Writer: w}
+}
+
+func (o *PrintObserver) OnTrainingEvent(event TrainingEvent) {
+	indent := strings.Repeat("  ", event.Depth)
+
+	switch event.Type {
+	case EventNode:
+		fmt.Fprintf(o.Writer, "%sBuildTree: depth=%d, dataset size=%d\n", indent, event.Depth, event.Size)
+		if event.Size > 0 {
+			fmt.Fprintf(o.Writer, "%s├─ Attack proportion: %.2f%%\n", indent, event.AttackProportion*100)
+		}
+	case EventSplit:
+		fmt.Fprintf(o.Writer, "%s├─ Best split: feature=%s, threshold=%d, gain=%.4f\n",
+			indent,
+			map[int]string{0: "RPM", 1: "Gear", 2: "Speed"}[event.Feature],
+			event.Threshold,
+			event.Gain)
+		fmt.Fprintf(o.Writer, "%s├─ Split result: left=%d samples, right=%d samples\n",
+			indent, event.LeftSize, event.RightSize)
+	case EventLeaf:
+		fmt.Fprintf(o.Writer, "%s└─ Leaf (%s), prediction=%v\n", indent, event.Reason, event.Prediction)
+	}
+}
@@ -0,0 +1,76 @@
+package ml
+
+// FeatureRange adalah interval integer inklusif [Min, Max]
+type FeatureRange struct {
+	Min int
+	Max int
+}
+
+// IsEmpty mengecek apakah interval tidak berisi nilai apapun
+func (r FeatureRange) IsEmpty() bool {
+	return r.Min > r.Max
+}
+
+// Size mengembalikan jumlah nilai integer di dalam interval
+func (r FeatureRange) Size() int {
+	if r.IsEmpty() {
+		return 0
+	}
+	return r.Max - r.Min + 1
+}
+
+// Intersect mengembalikan irisan dua interval
+func (r FeatureRange) Intersect(other FeatureRange) FeatureRange {
+	return FeatureRange{
+		Min: max(r.Min, other.Min),
+		Max: min(r.Max, other.Max),
+	}
+}
+
+// LeafRegion adalah hyper-rectangle dari satu leaf beserta prediksinya
+type LeafRegion struct {
+	Ranges     []FeatureRange // index sesuai Node.Feature
+	Prediction bool
+}
+
+// LeafRegions menghitung hyper-rectangle setiap leaf di dalam domain yang diberikan.
+// Leaf yang region-nya kosong di dalam domain tidak dikembalikan
+func (node *Node) LeafRegions(domain []FeatureRange) []LeafRegion {
+	var rules []Rule
+	node.collectRules(map[int]RuleCondition{}, &rules)
+
+	regions := make([]LeafRegion, 0, len(rules))
+	for _, rule := range rules {
+		ranges := make([]FeatureRange, len(domain))
+		copy(ranges, domain)
+
+		empty := false
+		for _, c := range rule.Conditions {
+			if c.Feature < 0 || c.Feature >= len(ranges) {
+				continue
+			}
+			r := ranges[c.Feature]
+			if c.HasLower {
+				r.Min = max(r.Min, c.Lower+1)
+			}
+			if c.HasUpper {
+				r.Max = min(r.Max, c.Upper)
+			}
+			ranges[c.Feature] = r
+			if r.IsEmpty() {
+				empty = true
+			}
+		}
+
+		if empty {
+			continue
+		}
+
+		regions = append(regions, LeafRegion{
+			Ranges:     ranges,
+			Prediction: rule.Prediction,
+		})
+	}
+
+	return regions
+}
@@ -0,0 +1,285 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+func init() {
+	RegisterComparator("tree.window", func() any { return &WindowTreeComparator{} })
+}
+
+// WindowFeatureKind adalah jenis feature turunan dari satu window
+type WindowFeatureKind string
+
+const (
+	WindowLast    WindowFeatureKind = "last"    // nilai terakhir
+	WindowDelta   WindowFeatureKind = "delta"   // selisih dua nilai terakhir
+	WindowRate    WindowFeatureKind = "rate"    // selisih dua nilai terakhir per detik
+	WindowMean    WindowFeatureKind = "mean"    // rata-rata window
+	WindowStdDev  WindowFeatureKind = "stddev"  // standar deviasi window
+	WindowMin     WindowFeatureKind = "min"     // nilai terkecil
+	WindowMax     WindowFeatureKind = "max"     // nilai terbesar
+	WindowSlope   WindowFeatureKind = "slope"   // kemiringan regresi linear per sampel
+	WindowChanges WindowFeatureKind = "changes" // berapa kali nilai berubah, misalnya jumlah pindah gear
+)
+
+// WindowFeatureSpec mendefinisikan satu kolom hasil transformasi.
+// Nilai dikali Scale lalu dibulatkan karena tree hanya bekerja dengan int
+type WindowFeatureSpec struct {
+	Feature string
+	Kind    WindowFeatureKind
+	Scale   float64
+}
+
+// Name adalah nama kolom, contoh "speed.rate"
+func (s WindowFeatureSpec) Name() string {
+	return s.Feature + "." + string(s.Kind)
+}
+
+// WindowSample adalah satu window yang sudah diubah menjadi FeatureProvider
+type WindowSample struct {
+	Record
+	Attack bool
+}
+
+func (s WindowSample) IsAnomaly() bool { return s.Attack }
+
+// WindowTransformer mengubah urutan data menjadi satu FeatureProvider per window,
+// sehingga decision tree bisa belajar serangan temporal seperti replay atau spoofing perlahan
+type WindowTransformer struct {
+	WindowSize int
+	Step       int // jarak antar window, 1 berarti setiap sampel
+	Specs      []WindowFeatureSpec
+
+	// LabelLast memberi label dari sampel terakhir saja,
+	// selain itu window dianggap anomali jika ada sampel anomali di dalamnya
+	LabelLast bool
+}
+
+// NewWindowTransformer membuat transformer dengan semua jenis feature untuk setiap nama feature.
+// Slope dikali 10 agar kemiringan kecil tidak hilang saat dibulatkan
+func NewWindowTransformer(windowSize int, features []string) *WindowTransformer {
+	kinds := []WindowFeatureKind{WindowLast, WindowDelta, WindowRate, WindowMean, WindowStdDev, WindowMin, WindowMax, WindowSlope, WindowChanges}
+
+	transformer := &WindowTransformer{WindowSize: windowSize, Step: 1}
+	for _, feature := range features {
+		for _, kind := range kinds {
+			scale := 1.0
+			if kind == WindowSlope {
+				scale = 10
+			}
+			transformer.Specs = append(transformer.Specs, WindowFeatureSpec{Feature: feature, Kind: kind, Scale: scale})
+		}
+	}
+	return transformer
+}
+
+// FeatureNames mengembalikan nama kolom sesuai urutan index feature
+func (t *WindowTransformer) FeatureNames() []string {
+	names := make([]string, len(t.Specs))
+	for i, spec := range t.Specs {
+		names[i] = spec.Name()
+	}
+	return names
+}
+
+// Transform mengubah seluruh urutan menjadi DataSet. Label diambil dari IsAnomaly
+// jika data mengimplementasikan HasAnomaly
+func (t *WindowTransformer) Transform(sequence []SequentialProvider) (DataSet, error) {
+	if t.WindowSize < 2 {
+		return nil, fmt.Errorf("window size must be at least 2, got %d", t.WindowSize)
+	}
+
+	step := max(t.Step, 1)
+	var dataset DataSet
+	for end := t.WindowSize; end <= len(sequence); end += step {
+		sample, err := t.TransformWindow(sequence[end-t.WindowSize : end])
+		if err != nil {
+			return nil, err
+		}
+		dataset = append(dataset, sample)
+	}
+	return dataset, nil
+}
+
+// TransformWindow mengubah satu window (paling lama di depan) menjadi WindowSample
+func (t *WindowTransformer) TransformWindow(window []SequentialProvider) (WindowSample, error) {
+	if len(window) < 2 {
+		return WindowSample{}, fmt.Errorf("window needs at least 2 samples, got %d", len(window))
+	}
+
+	last := window[len(window)-1]
+	sample := WindowSample{
+		Record: Record{
+			Timestamp: last.GetTimestamp(),
+			Names:     t.FeatureNames(),
+			Values:    make([]int, len(t.Specs)),
+		},
+	}
+
+	// Nilai setiap feature cukup dibaca sekali untuk semua spec
+	values := make(map[string][]float64)
+	for i, spec := range t.Specs {
+		series, exists := values[spec.Feature]
+		if !exists {
+			series = make([]float64, len(window))
+			for j, data := range window {
+				value, found := FeatureValueByName(data, spec.Feature)
+				if !found {
+					return WindowSample{}, fmt.Errorf("feature not found in data: %s", spec.Feature)
+				}
+				series[j] = value
+			}
+			values[spec.Feature] = series
+		}
+
+		value := windowFeature(spec.Kind, series, ElapsedSeconds(window[len(window)-2], last))
+		scale := spec.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		sample.Values[i] = int(math.Round(value * scale))
+	}
+
+	for i, data := range window {
+		labelled, ok := data.(HasAnomaly)
+		if !ok || (t.LabelLast && i < len(window)-1) {
+			continue
+		}
+		if labelled.IsAnomaly() {
+			sample.Attack = true
+		}
+	}
+
+	return sample, nil
+}
+
+func windowFeature(kind WindowFeatureKind, series []float64, elapsed float64) float64 {
+	n := len(series)
+	switch kind {
+	case WindowLast:
+		return series[n-1]
+	case WindowDelta:
+		return series[n-1] - series[n-2]
+	case WindowRate:
+		return (series[n-1] - series[n-2]) / elapsed
+	case WindowChanges:
+		changes := 0
+		for i := 1; i < n; i++ {
+			if series[i] != series[i-1] {
+				changes++
+			}
+		}
+		return float64(changes)
+	}
+
+	stats := CalculateWindowStats(series)
+	switch kind {
+	case WindowMean:
+		return stats.Mean
+	case WindowStdDev:
+		return stats.StdDev
+	case WindowMin:
+		return stats.Min
+	case WindowMax:
+		return stats.Max
+	case WindowSlope:
+		return stats.Slope
+	}
+	return 0
+}
+
+// WindowTreeComparator menjalankan tree yang dilatih dengan WindowTransformer di dalam WindowDetector.
+// WindowSize detector harus minimal WindowSize transformer, AddFeatureConfig menolak detector yang lebih kecil.
+// Window yang lebih pendek tidak dinilai dan dilaporkan sebagai error
+type WindowTreeComparator struct {
+	Transformer *WindowTransformer
+	Tree        *Node
+}
+
+// NewWindowTreeComparator memastikan semua feature yang dipakai tree ada di transformer
+func NewWindowTreeComparator(transformer *WindowTransformer, tree *Node) (*WindowTreeComparator, error) {
+	comparator := &WindowTreeComparator{Transformer: transformer, Tree: tree}
+	if err := comparator.Validate(); err != nil {
+		return nil, err
+	}
+	return comparator, nil
+}
+
+// Validate mengecek bahwa index feature tree sesuai dengan kolom transformer
+func (c *WindowTreeComparator) Validate() error {
+	if c.Transformer == nil || c.Tree == nil {
+		return fmt.Errorf("window tree comparator needs a transformer and a tree")
+	}
+	if c.Transformer.WindowSize < 2 {
+		return fmt.Errorf("window size must be at least 2, got %d", c.Transformer.WindowSize)
+	}
+	if err := c.Tree.validateFeatures(c.Transformer.FeatureNames()); err != nil {
+		return fmt.Errorf("tree does not match the window features: %v", err)
+	}
+	return nil
+}
+
+// UnmarshalJSON memvalidasi comparator yang dibaca dari konfigurasi atau snapshot
+func (c *WindowTreeComparator) UnmarshalJSON(data []byte) error {
+	type plain WindowTreeComparator
+	if err := json.Unmarshal(data, (*plain)(c)); err != nil {
+		return err
+	}
+	if c.Transformer == nil && c.Tree == nil {
+		return nil
+	}
+	return c.Validate()
+}
+
+func (c *WindowTreeComparator) CompareRecords(prev, current SequentialProvider, window []SequentialProvider) (float64, string) {
+	score, reason, err := c.CompareRecordsWithError(prev, current, window)
+	if err != nil {
+		return 0, ""
+	}
+	return score, reason
+}
+
+// CompareRecordsWithError sama dengan CompareRecords, tetapi mengembalikan error
+// jika window lebih pendek dari WindowSize transformer atau tidak bisa ditransformasi
+func (c *WindowTreeComparator) CompareRecordsWithError(prev, current SequentialProvider, window []SequentialProvider) (float64, string, error) {
+	if c.Transformer == nil || c.Tree == nil {
+		return 0, "", fmt.Errorf("window tree comparator needs a transformer and a tree")
+	}
+
+	// Pakai sampel terakhir sebanyak WindowSize transformer
+	size := c.Transformer.WindowSize
+	if len(window) < size {
+		return 0, "", fmt.Errorf("window tree needs %d samples, got %d", size, len(window))
+	}
+	window = window[len(window)-size:]
+
+	sample, err := c.Transformer.TransformWindow(window)
+	if err != nil {
+		return 0, "", err
+	}
+	if !c.Tree.Predict(sample) {
+		return 0, "", nil
+	}
+
+	// Tampilkan nilai feature yang dipakai di jalur keputusan tree
+	var used []string
+	names := c.Transformer.FeatureNames()
+	for node := c.Tree; node != nil && !node.IsLeaf; {
+		value := sample.GetFeatureValue(node.Feature)
+		name := fmt.Sprintf("feature %d", node.Feature)
+		if node.Feature >= 0 && node.Feature < len(names) {
+			name = names[node.Feature]
+		}
+		used = append(used, fmt.Sprintf("%s=%d", name, value))
+		if value <= node.Threshold {
+			node = node.Left
+		} else {
+			node = node.Right
+		}
+	}
+	return 1.0, fmt.Sprintf("window tree predicts attack (%s)", strings.Join(used, ", ")), nil
+}
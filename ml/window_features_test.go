@@ -0,0 +1,34 @@
+package ml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWindowTreeComparatorRejectsShortWindows(t *testing.T) {
+	transformer := NewWindowTransformer(4, []string{"rpm"})
+	comparator, err := NewWindowTreeComparator(transformer, &Node{IsLeaf: true, Prediction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	window := []SequentialProvider{testRecord(0, 2000, 2, 30), testRecord(1, 2100, 2, 31), testRecord(2, 2200, 2, 32)}
+	score, _, err := comparator.CompareRecordsWithError(window[1], window[2], window)
+	if err == nil || score != 0 {
+		t.Errorf("window of 3 samples for a tree trained on 4: score %v, error %v", score, err)
+	}
+
+	window = append(window, testRecord(3, 2300, 2, 33))
+	if score, _, err := comparator.CompareRecordsWithError(window[2], window[3], window); err != nil || score != 1 {
+		t.Errorf("full window: score %v, error %v", score, err)
+	}
+
+	// Detector dengan window lebih kecil ditolak saat comparator ditambahkan
+	err = NewWindowDetector(3).AddFeatureConfig(FeatureConfig{Name: "tree", Threshold: 0.5, RecordComparator: comparator})
+	if err == nil || !strings.Contains(err.Error(), "4 samples") {
+		t.Errorf("expected window size error, got %v", err)
+	}
+	if err := NewWindowDetector(4).AddFeatureConfig(FeatureConfig{Name: "tree", Threshold: 0.5, RecordComparator: comparator}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,170 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+)
+
+// WindowStats adalah statistik satu feature di dalam window
+type WindowStats struct {
+	Count  int
+	Mean   float64
+	StdDev float64
+	Min    float64
+	Max    float64
+	Slope  float64 // perubahan rata-rata per sampel (least squares)
+	ZScore float64 // posisi nilai terakhir terhadap sampel sebelumnya di window
+}
+
+// ZScoreMinStdDev adalah batas bawah standar deviasi pembagi z-score.
+// Nilai feature berupa integer, jadi sampel sebelumnya yang konstan dianggap bervariasi satu unit
+// dan lonjakan setelah window yang datar tetap menghasilkan z-score yang besar tetapi terbatas
+const ZScoreMinStdDev = 1.0
+
+// CalculateWindowStats menghitung statistik dari nilai feature, urut dari yang paling lama
+func CalculateWindowStats(values []float64) WindowStats {
+	stats := WindowStats{Count: len(values)}
+	if len(values) == 0 {
+		return stats
+	}
+
+	stats.Mean, stats.StdDev = meanStdDev(values)
+	stats.Min, stats.Max = values[0], values[0]
+	for _, v := range values {
+		stats.Min = math.Min(stats.Min, v)
+		stats.Max = math.Max(stats.Max, v)
+	}
+
+	// Slope dengan regresi linear terhadap index sampel
+	n := float64(len(values))
+	meanX := (n - 1) / 2
+	var num, den float64
+	for i, v := range values {
+		dx := float64(i) - meanX
+		num += dx * (v - stats.Mean)
+		den += dx * dx
+	}
+	if den > 0 {
+		stats.Slope = num / den
+	}
+
+	// Z-score nilai terakhir dibandingkan dengan sampel sebelumnya, dengan standar deviasi
+	// minimal ZScoreMinStdDev agar tetap terdefinisi jika sampel sebelumnya konstan
+	if len(values) > 1 {
+		current := values[len(values)-1]
+		mean, stdDev := meanStdDev(values[:len(values)-1])
+		stats.ZScore = (current - mean) / max(stdDev, ZScoreMinStdDev)
+	}
+
+	return stats
+}
+
+func meanStdDev(values []float64) (mean float64, stdDev float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	for _, v := range values {
+		stdDev += (v - mean) * (v - mean)
+	}
+	stdDev = math.Sqrt(stdDev / float64(len(values)))
+
+	return mean, stdDev
+}
+
+// WindowComparator membandingkan seluruh isi window, bukan hanya dua sampel terakhir
+type WindowComparator interface {
+	// CompareWindow menerima nilai feature di window (paling lama di depan)
+	// dan mengembalikan skor yang dibandingkan dengan threshold
+	CompareWindow(values []float64) float64
+}
+
+// WindowCheck adalah satu pengecekan window dengan threshold-nya sendiri
+type WindowCheck struct {
+	Threshold  float64
+	Comparator WindowComparator
+}
+
+// WindowStatistic memilih statistik yang dipakai StatComparator
+type WindowStatistic int
+
+const (
+	StatMean WindowStatistic = iota
+	StatStdDev
+	StatMin
+	StatMax
+	StatRange
+	StatSlope
+	StatZScore
+)
+
+func (s WindowStatistic) String() string {
+	switch s {
+	case StatMean:
+		return "mean"
+	case StatStdDev:
+		return "stddev"
+	case StatMin:
+		return "min"
+	case StatMax:
+		return "max"
+	case StatRange:
+		return "range"
+	case StatSlope:
+		return "slope"
+	case StatZScore:
+		return "zscore"
+	default:
+		return fmt.Sprintf("statistic(%d)", int(s))
+	}
+}
+
+// MarshalText menulis statistik sebagai nama, misalnya "zscore"
+func (s WindowStatistic) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText membaca statistik dari nama
+func (s *WindowStatistic) UnmarshalText(text []byte) error {
+	for stat := StatMean; stat <= StatZScore; stat++ {
+		if stat.String() == string(text) {
+			*s = stat
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown window statistic: %s", text)
+}
+
+// StatComparator mengembalikan satu statistik window sebagai skor.
+// Slope dan z-score dikembalikan sebagai nilai absolut
+type StatComparator struct {
+	Statistic WindowStatistic
+}
+
+func (c StatComparator) CompareWindow(values []float64) float64 {
+	stats := CalculateWindowStats(values)
+
+	switch c.Statistic {
+	case StatMean:
+		return stats.Mean
+	case StatStdDev:
+		return stats.StdDev
+	case StatMin:
+		return stats.Min
+	case StatMax:
+		return stats.Max
+	case StatRange:
+		return stats.Max - stats.Min
+	case StatSlope:
+		return math.Abs(stats.Slope)
+	case StatZScore:
+		return math.Abs(stats.ZScore)
+	default:
+		return 0
+	}
+}
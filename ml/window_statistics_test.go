@@ -0,0 +1,31 @@
+package ml
+
+import (
+	"math"
+	"testing"
+)
+
+func TestZScoreAfterFlatWindow(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   float64
+	}{
+		{[]float64{2000, 2000, 2000, 2000}, 0},
+		{[]float64{2000, 2000, 2000, 2600}, 600}, // sampel sebelumnya konstan, dibagi ZScoreMinStdDev
+		{[]float64{2000, 2000, 2000, 1400}, -600},
+		{[]float64{10, 30, 10, 30, 60}, 4}, // mean 20, standar deviasi 10
+	}
+
+	for _, test := range tests {
+		stats := CalculateWindowStats(test.values)
+		if math.Abs(stats.ZScore-test.want) > 1e-9 {
+			t.Errorf("%v: z-score %v, want %v", test.values, stats.ZScore, test.want)
+		}
+	}
+
+	// Lonjakan setelah window yang datar melewati threshold z-score yang biasa
+	score := StatComparator{Statistic: StatZScore}.CompareWindow([]float64{50, 50, 50, 50, 80})
+	if score <= 3 {
+		t.Errorf("spike after flat window scored %v", score)
+	}
+}